@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata" // Embed zone data; containers may not ship /usr/share/zoneinfo
 
+	"notification_batch/internal/api"
+	"notification_batch/internal/batch/encb"
+	"notification_batch/internal/batch/spending_alert"
 	"notification_batch/internal/config"
+	"notification_batch/internal/dispatcher"
+	"notification_batch/internal/leader"
 	"notification_batch/internal/logger"
+	"notification_batch/internal/msgtemplate"
+	"notification_batch/internal/redact"
 	"notification_batch/internal/routes"
 	"notification_batch/internal/scheduler"
+	"notification_batch/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,8 +39,15 @@ func main() {
 		return
 	}
 
+	// Initialize Log Redaction
+	redactor, err := redact.New(defaultCfg.Redaction, defaultCfg.Masking.CardNoFormat)
+	if err != nil {
+		log.Fatalf("Failed to initialize log redaction: %v", err)
+		return
+	}
+
 	// Initialize Logger
-	err := logger.InitLogger(defaultCfg.LogPath)
+	err = logger.InitLogger(defaultCfg.LogPath, redactor)
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 		return
@@ -38,26 +56,73 @@ func main() {
 
 	logger.AppLogger.Sugar().Info("Application is starting with Gin Framework...")
 
+	// Configure Request ID Generator
+	if err := util.SetRequestIDNode(*defaultCfg.RequestIDNode); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Configure Business Time Zone
+	if err := util.SetTimezone(defaultCfg.Timezone); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Invalid configuration: %v", err)
+	}
+	logger.AppLogger.Sugar().Infof("Using business time zone %s", util.Location())
+
+	// Validate API and Batch Configuration
+	if err := api.ValidateConfig(defaultCfg); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Invalid configuration: %v", err)
+	}
+	if err := spending_alert.ValidateConfig(defaultCfg); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Invalid configuration: %v", err)
+	}
+	if err := encb.ValidateConfig(defaultCfg); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Load Message Templates
+	if _, err := msgtemplate.Get(defaultCfg.TemplateFile); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Failed to load message templates: %v", err)
+	}
+
 	// Initialize Gin Router
 	router = gin.Default()
 
+	// Initialize Dispatcher
+	if err := dispatcher.InitDispatcher(defaultCfg); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Failed to initialize dispatcher: %v", err)
+	}
+
+	// Initialize Leader Election
+	if err := leader.InitElector(defaultCfg); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Failed to initialize leader election: %v", err)
+	}
+
 	// Initialize Scheduler
-	scheduler.InitScheduler(cfgMap)
+	if err := scheduler.InitScheduler(cfgMap); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Failed to initialize scheduler: %v", err)
+	}
 
 	// Initialize Application and Setup Routes
-	routes.Init(router, nil, cfgMap)
+	if err := routes.Init(router, scheduler.Get(), cfgMap); err != nil {
+		logger.AppLogger.Sugar().Fatalf("Failed to initialize routes: %v", err)
+	}
 
-	// Start the Scheduler
+	// Start the Dispatcher, Leader Election and Scheduler
+	dispatcher.StartDispatcher()
+	leader.StartElector()
 	scheduler.StartScheduler()
 
 	// Start the Gin HTTP Server
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080" // Default port
+	}
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
 	go func() {
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = "8080" // Default port
-		}
 		logger.AppLogger.Sugar().Infof("Gin server listening on port %s", port)
-		if err := router.Run(":" + port); err != nil && err != http.ErrServerClosed {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.AppLogger.Sugar().Fatalf("Failed to start Gin server: %v", err)
 		}
 	}()
@@ -69,10 +134,36 @@ func main() {
 
 	logger.AppLogger.Info("Application is shutting down...")
 
-	// Stop the Scheduler
-	scheduler.StopScheduler()
+	// Drain HTTP requests, running batches and in-flight sends within the grace period
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCfg.ShutdownGracePeriod*time.Second)
+	defer cancel()
 
+	drained := true
 	logger.AppLogger.Info("Gin server shutting down...")
+	if err := server.Shutdown(ctx); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to shut down Gin server: %v", err)
+		drained = false
+	}
+
+	// Stop the Scheduler and Dispatcher
+	if err := scheduler.StopScheduler(ctx); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to stop scheduler: %v", err)
+		drained = false
+	}
+	if err := dispatcher.StopDispatcher(ctx); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to stop dispatcher: %v", err)
+		drained = false
+	}
 
+	// Hand over leadership once no batch is running
+	if err := leader.StopElector(); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to stop leader election: %v", err)
+	}
+
+	if !drained {
+		logger.AppLogger.Error("Application did not stop cleanly within the grace period.")
+		logger.AppLogger.Sync()
+		os.Exit(1)
+	}
 	logger.AppLogger.Info("Application has stopped.")
 }
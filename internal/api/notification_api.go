@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,7 +10,9 @@ import (
 	"time"
 
 	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
 	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
 	"notification_batch/internal/model"
 )
 
@@ -30,46 +33,67 @@ func NewNotificationClient(cfg *config.Config) *NotificationClient {
 }
 
 // SendNotification sends a notification request.
-func (c *NotificationClient) SendNotification(request model.NotificationRequest) (*model.NotificationResponse, error) {
+// A response whose ResponseCode is not mapped to success is returned together with a *ResponseError.
+// The run and correlation IDs carried by ctx are sent as headers and logged with the call;
+// the correlation ID is also set in the request body.
+func (c *NotificationClient) SendNotification(ctx context.Context, request model.NotificationRequest) (*model.NotificationResponse, error) {
 	apiURL := c.cfg.APIEndpoints.SendNotification
+	fields := logger.ContextFields(ctx)
+	request.CorrelationID = correlation.CorrelationID(ctx)
 
-	logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Calling Send Notification API - Request: %+v, URL: %s", request, apiURL))
+	logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Calling Send Notification API - Request: %s, URL: %s", logger.Redact(request), apiURL), fields...)
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(correlation.HeaderRunID, correlation.RunID(ctx))
+	req.Header.Set(correlation.HeaderCorrelationID, correlation.CorrelationID(ctx))
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Failed Response (Error: %v), URL: %s", err, apiURL))
+		metrics.ObserveAPICall(metrics.APINotification, start, 0)
+		logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Failed Response (Error: %v), URL: %s", err, apiURL), fields...)
 		return nil, fmt.Errorf("failed to call API: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveAPICall(metrics.APINotification, start, resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
 		errBodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Get Alert Setting API - Non-OK Status: %d, URL: %s, Error reading body: %v", resp.StatusCode, apiURL, err))
+			logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Get Alert Setting API - Non-OK Status: %d, URL: %s, Error reading body: %v", resp.StatusCode, apiURL, err), fields...)
 		} else {
-			logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Get Alert Setting API - Non-OK Status: %d, URL: %s, Body: %s", resp.StatusCode, apiURL, string(errBodyBytes)))
+			logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Get Alert Setting API - Non-OK Status: %d, URL: %s, Body: %s", resp.StatusCode, apiURL, logger.RedactBody(errBodyBytes)), fields...)
 		}
-		return nil, fmt.Errorf("API returned non-OK status: %d", resp.StatusCode)
+		return nil, &ResponseError{StatusCode: resp.StatusCode, Outcome: statusOutcome(resp.StatusCode)}
 	}
 
 	response := &model.NotificationResponse{}
 	err = json.NewDecoder(resp.Body).Decode(response)
 	if err != nil {
-		logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Failed to decode response: %v, URL: %s", err, apiURL))
+		logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Failed to decode response: %v, URL: %s", err, apiURL), fields...)
 		return nil, fmt.Errorf("failed to decode API response: %w", err)
 	}
 
-	logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Successful Response: %+v, URL: %s", response, apiURL))
+	outcome := ClassifyResponseCode(c.cfg.APIEndpoints.ResponseCodes.Notification, response.ResponseCode)
+	if outcome != OutcomeSuccess {
+		logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Failed Response Code (%s): %s, URL: %s", outcome, logger.Redact(response), apiURL), fields...)
+		return response, &ResponseError{
+			StatusCode:   resp.StatusCode,
+			ResponseCode: response.ResponseCode,
+			Message:      response.ResponseMessage,
+			Outcome:      outcome,
+		}
+	}
+
+	logger.ApiLogger(c.cfg.LogPath, c.cfg.APILogPrefix, fmt.Sprintf("Send Notification API - Successful Response: %s, URL: %s", logger.Redact(response), apiURL), fields...)
 	return response, nil
 }
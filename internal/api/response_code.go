@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"notification_batch/internal/config"
+)
+
+// Outcome classifies an API result for retry decisions and result status columns.
+type Outcome string
+
+// Supported outcomes.
+const (
+	OutcomeSuccess   Outcome = "success"
+	OutcomeRetryable Outcome = "retryable"
+	OutcomePermanent Outcome = "permanent"
+)
+
+// ResponseError is returned when an API call fails, carrying the business response code if one was received.
+type ResponseError struct {
+	StatusCode   int
+	ResponseCode string
+	Message      string
+	Outcome      Outcome
+}
+
+func (e *ResponseError) Error() string {
+	if e.ResponseCode != "" {
+		return fmt.Sprintf("API returned %s response code '%s': %s", e.Outcome, e.ResponseCode, e.Message)
+	}
+	return fmt.Sprintf("API returned non-OK status: %d", e.StatusCode)
+}
+
+// ClassifyResponseCode maps a business response code to an outcome using the configured mapping.
+// Codes not listed fall back to the mapping default, or permanent if no default is set.
+func ClassifyResponseCode(mapping config.ResponseCodeMapping, code string) Outcome {
+	for _, c := range mapping.Success {
+		if c == code {
+			return OutcomeSuccess
+		}
+	}
+	for _, c := range mapping.Retryable {
+		if c == code {
+			return OutcomeRetryable
+		}
+	}
+	for _, c := range mapping.Permanent {
+		if c == code {
+			return OutcomePermanent
+		}
+	}
+	if mapping.Default != "" {
+		return Outcome(mapping.Default)
+	}
+	return OutcomePermanent
+}
+
+// ValidateConfig checks the API response code mappings at startup.
+func ValidateConfig(cfg *config.Config) error {
+	if err := validateResponseCodeMapping(cfg.APIEndpoints.ResponseCodes.Notification); err != nil {
+		return fmt.Errorf("api_endpoints.response_codes.notification: %v", err)
+	}
+	if err := validateResponseCodeMapping(cfg.APIEndpoints.ResponseCodes.AlertSetting); err != nil {
+		return fmt.Errorf("api_endpoints.response_codes.alert_setting: %v", err)
+	}
+	return nil
+}
+
+func validateResponseCodeMapping(mapping config.ResponseCodeMapping) error {
+	switch Outcome(mapping.Default) {
+	case "", OutcomeSuccess, OutcomeRetryable, OutcomePermanent:
+		return nil
+	}
+	return fmt.Errorf("invalid default '%s'", mapping.Default)
+}
+
+// IsRetryable reports whether err is worth retrying. Transport errors, 5xx/429 statuses and
+// retryable response codes are retryable; other API errors are permanent.
+func IsRetryable(err error) bool {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.Outcome == OutcomeRetryable
+	}
+	return err != nil
+}
+
+// statusOutcome classifies a non-OK HTTP status.
+func statusOutcome(statusCode int) Outcome {
+	if statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests {
+		return OutcomeRetryable
+	}
+	return OutcomePermanent
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/model"
+)
+
+var testMapping = config.ResponseCodeMapping{
+	Success:   []string{"0000"},
+	Retryable: []string{"9001", "9002"},
+	Permanent: []string{"4001"},
+}
+
+func TestClassifyResponseCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping config.ResponseCodeMapping
+		code    string
+		want    Outcome
+	}{
+		{"success", testMapping, "0000", OutcomeSuccess},
+		{"retryable", testMapping, "9002", OutcomeRetryable},
+		{"permanent", testMapping, "4001", OutcomePermanent},
+		{"unlisted without default", testMapping, "5555", OutcomePermanent},
+		{"empty code", testMapping, "", OutcomePermanent},
+		{"unlisted with default", config.ResponseCodeMapping{Success: []string{"0000"}, Default: "retryable"}, "5555", OutcomeRetryable},
+		{"listed code beats default", config.ResponseCodeMapping{Permanent: []string{"4001"}, Default: "retryable"}, "4001", OutcomePermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyResponseCode(tt.mapping, tt.code); got != tt.want {
+				t.Errorf("ClassifyResponseCode(%q) = %s, want %s", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"transport error", errors.New("connection refused"), true},
+		{"retryable response code", &ResponseError{ResponseCode: "9001", Outcome: OutcomeRetryable}, true},
+		{"permanent response code", &ResponseError{ResponseCode: "4001", Outcome: OutcomePermanent}, false},
+		{"wrapped retryable", fmt.Errorf("send: %w", &ResponseError{StatusCode: 503, Outcome: statusOutcome(503)}), true},
+		{"status 429", &ResponseError{StatusCode: 429, Outcome: statusOutcome(429)}, true},
+		{"status 400", &ResponseError{StatusCode: 400, Outcome: statusOutcome(400)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, def := range []string{"", "success", "retryable", "permanent"} {
+		cfg := &config.Config{}
+		cfg.APIEndpoints.ResponseCodes.Notification.Default = def
+		if err := ValidateConfig(cfg); err != nil {
+			t.Errorf("ValidateConfig() with default %q error = %v", def, err)
+		}
+	}
+	cfg := &config.Config{}
+	cfg.APIEndpoints.ResponseCodes.AlertSetting.Default = "retry"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("ValidateConfig() with default \"retry\" error = nil, want an error")
+	}
+}
+
+func TestSendNotificationClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantErr       bool
+		wantRetryable bool
+		wantResponse  bool
+	}{
+		{"success code", http.StatusOK, `{"ResponseCode":"0000"}`, false, false, true},
+		{"retryable code", http.StatusOK, `{"ResponseCode":"9001","ResponseMessage":"busy"}`, true, true, true},
+		{"permanent code", http.StatusOK, `{"ResponseCode":"4001","ResponseMessage":"invalid token"}`, true, false, true},
+		{"server error", http.StatusBadGateway, ``, true, true, false},
+		{"client error", http.StatusBadRequest, ``, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{LogPath: t.TempDir(), APILogPrefix: "api"}
+			cfg.APIEndpoints.SendNotification = server.URL
+			cfg.APIEndpoints.Timeout = 5
+			cfg.APIEndpoints.ResponseCodes.Notification = testMapping
+
+			response, err := NewNotificationClient(cfg).SendNotification(context.Background(), model.NotificationRequest{Usertoken: "token"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendNotification() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got := IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+			if (response != nil) != tt.wantResponse {
+				t.Errorf("response = %+v, want a response %t", response, tt.wantResponse)
+			}
+		})
+	}
+}
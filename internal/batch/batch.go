@@ -0,0 +1,37 @@
+package batch
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"notification_batch/internal/util"
+)
+
+// RunOptions adjusts a single batch run; the zero value processes every file for today.
+type RunOptions struct {
+	// FileName restricts a send run to one remote file.
+	FileName string
+	// BusinessDate is the date used for result file names and delivery reports.
+	BusinessDate time.Time
+}
+
+// Date returns the business date of the run in the business time zone, defaulting to today.
+func (o RunOptions) Date() time.Time {
+	if o.BusinessDate.IsZero() {
+		return util.Now()
+	}
+	return o.BusinessDate.In(util.Location())
+}
+
+// ResultFileName returns the name of a result file with prefix for the business date of the run,
+// e.g. "sa_result_20261019.txt". A run restricted to one file adds its name, e.g.
+// "sa_result_20261019_alerts_0930.txt", so runs of several files on one day keep their results.
+func (o RunOptions) ResultFileName(prefix string) string {
+	date := o.Date().Format("20060102")
+	if o.FileName != "" {
+		return fmt.Sprintf("%s_%s_%s.txt", prefix, date, strings.TrimSuffix(o.FileName, filepath.Ext(o.FileName)))
+	}
+	return fmt.Sprintf("%s_%s.txt", prefix, date)
+}
@@ -0,0 +1,40 @@
+package batch
+
+import (
+	"testing"
+	"time"
+
+	"notification_batch/internal/util"
+)
+
+func TestRunOptionsUseBusinessTimezone(t *testing.T) {
+	previous := util.Location()
+	if err := util.SetTimezone("Asia/Bangkok"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { util.SetTimezone(previous.String()) })
+
+	// 23:00 on 19 October in UTC is already 20 October in Bangkok.
+	opts := RunOptions{BusinessDate: time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)}
+	if got := opts.Date().Format("2006-01-02"); got != "2026-10-20" {
+		t.Errorf("Date() = %s, want 2026-10-20", got)
+	}
+	if got := opts.ResultFileName("sa_result"); got != "sa_result_20261020.txt" {
+		t.Errorf("ResultFileName() = %s, want sa_result_20261020.txt", got)
+	}
+	if got := (RunOptions{}).Date().Location(); got != util.Location() {
+		t.Errorf("Date() of today is in %s, want %s", got, util.Location())
+	}
+}
+
+func TestResultFileNameOfWatchedFiles(t *testing.T) {
+	date := time.Date(2026, 10, 19, 9, 0, 0, 0, util.Location())
+	first := RunOptions{FileName: "alerts_0930.csv", BusinessDate: date}.ResultFileName("sa_result")
+	second := RunOptions{FileName: "alerts_1430.csv", BusinessDate: date}.ResultFileName("sa_result")
+	if first != "sa_result_20261019_alerts_0930.txt" {
+		t.Errorf("ResultFileName() = %s, want sa_result_20261019_alerts_0930.txt", first)
+	}
+	if first == second {
+		t.Errorf("ResultFileName() = %s for both files of the day", first)
+	}
+}
@@ -1,30 +1,61 @@
 package encb
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
 
+	"notification_batch/internal/batch"
+	"notification_batch/internal/checkpoint"
 	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
 	"notification_batch/internal/ftp"
 	"notification_batch/internal/logger"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/runs"
 	"notification_batch/internal/util"
 )
 
+// batchName identifies e-NCB notifications in shared stores.
+const batchName = "encb"
+
+// checkpointPath returns the directory holding per-file processing checkpoints.
+func checkpointPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataPath, batchName, "checkpoints")
+}
+
+// clearCheckpoint removes the checkpoint of a file whose results have been delivered.
+func clearCheckpoint(ctx context.Context, cfg *config.Config, filePath string) {
+	cp, err := checkpoint.Open(checkpointPath(cfg), filePath)
+	if err == nil {
+		err = cp.Clear()
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to clear checkpoint for '%s': %v", filePath, err)
+	}
+}
+
 // Run e-NCB Send Batch process.
-func RunENCBSendBatch(cfg *config.Config) {
-	logger.AppLogger.Info("Starting e-NCB Send Batch...")
-	defer logger.AppLogger.Info("e-NCB Send Batch finished.")
+// Cancelling ctx stops the batch after the current record; progress is checkpointed
+// so the next run resumes the interrupted file.
+// An error is returned if the batch could not run or any file failed.
+func RunENCBSendBatch(ctx context.Context, cfg *config.Config, opts batch.RunOptions) error {
+	if correlation.RunID(ctx) == "" {
+		ctx = correlation.WithRunID(ctx, correlation.NewRunID(batchName))
+	}
+	log := logger.FromContext(ctx)
 
-	ftpClient, err := ftp.NewClient(ftp.Config{
+	log.Info("Starting e-NCB Send Batch...")
+	defer log.Info("e-NCB Send Batch finished.")
+
+	ftpClient, err := ftp.NewClient(ctx, ftp.Config{
 		Host:     cfg.ENCB.FTP.Host,
 		User:     cfg.ENCB.FTP.User,
 		Password: cfg.ENCB.FTP.Password,
 	})
 	if err != nil {
-		logger.AppLogger.Sugar().Errorf("Failed to create FTP client for e-NCB: %v", err)
-		return
+		return fmt.Errorf("failed to create FTP client for e-NCB: %v", err)
 	}
 	defer ftpClient.Close()
 
@@ -33,61 +64,177 @@ func RunENCBSendBatch(cfg *config.Config) {
 
 	if _, err := os.Stat(localDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(localDir, 0755); err != nil {
-			logger.AppLogger.Sugar().Errorf("Failed to create local directory '%s': %v", localDir, err)
-			return
+			return fmt.Errorf("failed to create local directory '%s': %v", localDir, err)
 		}
 	}
 
-	files, err := ftpClient.ListFiles(remotePath)
+	files, err := ftpClient.ListFiles(ctx, remotePath)
 	if err != nil {
-		logger.AppLogger.Sugar().Errorf("Failed to list files on FTP '%s': %v", remotePath, err)
-		return
+		return fmt.Errorf("failed to list files on FTP '%s': %v", remotePath, err)
+	}
+	if opts.FileName != "" {
+		if !containsFile(files, opts.FileName) {
+			return fmt.Errorf("file '%s' not found on FTP '%s'", opts.FileName, remotePath)
+		}
+		files = []string{opts.FileName}
 	}
 
+	recorder := runs.FromContext(ctx)
+	failed := 0
 	for _, file := range files {
-		localFilePath, err := ftpClient.DownloadFile(filepath.Join(remotePath, file), localDir)
+		if ctx.Err() != nil {
+			log.Warnf("e-NCB Send Batch interrupted: %v", ctx.Err())
+			return ctx.Err()
+		}
+
+		recorder.FileStarted(file)
+		localFilePath, err := ftpClient.DownloadFile(ctx, filepath.Join(remotePath, file), localDir)
 		if err != nil {
-			logger.AppLogger.Sugar().Errorf("Failed to download file '%s': %v", file, err)
+			log.Errorf("Failed to download file '%s': %v", file, err)
+			recorder.FileFinished(file, err)
+			failed++
 			continue
 		}
-		logger.AppLogger.Sugar().Infof("Downloaded file '%s' to '%s'", file, localFilePath)
+		log.Infof("Downloaded file '%s' to '%s'", file, localFilePath)
 
-		results, err := ProcessENCBFile(cfg, localFilePath)
+		results, err := ProcessENCBFile(ctx, cfg, localFilePath)
+		if err != nil && ctx.Err() != nil {
+			log.Warnf("Processing of file '%s' interrupted, progress checkpointed: %v", localFilePath, err)
+			recorder.FileFinished(file, err)
+			return err
+		}
 		if err != nil {
-			logger.AppLogger.Sugar().Errorf("Failed to process file '%s': %v", localFilePath, err)
+			log.Errorf("Failed to process file '%s': %v", localFilePath, err)
+			recorder.FileFinished(file, err)
+			failed++
 			continue
 		}
 
 		if len(results) > 0 {
-			resultFileName := fmt.Sprintf("%s_%s.txt", cfg.ENCB.ResultPrefix, time.Now().Format("20060102"))
+			resultFileName := opts.ResultFileName(cfg.ENCB.ResultPrefix)
 			resultFilePath := filepath.Join(cfg.ENCB.FTP.LocalPath, resultFileName)
 			err = util.WriteResultToFile(resultFilePath, results)
 			if err != nil {
-				logger.AppLogger.Sugar().Errorf("Failed to write result to file '%s': %v", resultFilePath, err)
+				log.Errorf("Failed to write result to file '%s': %v", resultFilePath, err)
+				recorder.FileFinished(file, err)
+				failed++
 				continue
 			}
-			logger.AppLogger.Sugar().Infof("Wrote result to file '%s'", resultFilePath)
+			log.Infof("Wrote result to file '%s'", resultFilePath)
 
 			remoteResultPath := cfg.ENCB.FTP.RemotePathResult
-			err = ftpClient.UploadFile(resultFilePath, filepath.Join(remoteResultPath, resultFileName))
+			err = ftpClient.UploadFile(ctx, resultFilePath, filepath.Join(remoteResultPath, resultFileName))
 			if err != nil {
-				logger.AppLogger.Sugar().Errorf("Failed to upload result file '%s' to '%s': %v", resultFilePath, remoteResultPath, err)
+				log.Errorf("Failed to upload result file '%s' to '%s': %v", resultFilePath, remoteResultPath, err)
+				recorder.FileFinished(file, err)
+				failed++
 			} else {
-				logger.AppLogger.Sugar().Infof("Uploaded result file '%s' to '%s'", resultFilePath, remoteResultPath)
+				log.Infof("Uploaded result file '%s' to '%s'", resultFilePath, remoteResultPath)
+				recorder.FileFinished(file, nil)
+				clearCheckpoint(ctx, cfg, localFilePath)
 				// Optionally delete the local file after successful upload
 				os.Remove(localFilePath)
 				os.Remove(resultFilePath)
 			}
 		} else {
+			recorder.FileFinished(file, nil)
+			clearCheckpoint(ctx, cfg, localFilePath)
 			// Optionally delete the local file even if no results were processed
 			os.Remove(localFilePath)
 		}
 	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+// deliveryOutcome maps a notification queue state to its run outcome.
+func deliveryOutcome(state string) string {
+	switch state {
+	case queue.StateSent:
+		return runs.OutcomeSent
+	case queue.StateDeadLetter:
+		return runs.OutcomeFailed
+	default:
+		return runs.OutcomePending
+	}
+}
+
+func containsFile(files []string, name string) bool {
+	for _, file := range files {
+		if file == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Run e-NCB Result Batch process.
-func RunENCBResultBatch(cfg *config.Config) {
-	logger.AppLogger.Info("Starting e-NCB Result Batch...")
-	defer logger.AppLogger.Info("e-NCB Result Batch finished.")
-	// Add logic for e-NCB Result Batch
+// The delivery report covers notifications created on the run's business date.
+func RunENCBResultBatch(ctx context.Context, cfg *config.Config, opts batch.RunOptions) error {
+	if correlation.RunID(ctx) == "" {
+		ctx = correlation.WithRunID(ctx, correlation.NewRunID(batchName))
+	}
+	log := logger.FromContext(ctx)
+
+	log.Info("Starting e-NCB Result Batch...")
+	defer log.Info("e-NCB Result Batch finished.")
+
+	outbox, err := queue.Open(queue.OutboxPath(cfg.DataPath))
+	if err != nil {
+		return fmt.Errorf("failed to open notification queue: %v", err)
+	}
+
+	businessDate := opts.Date()
+	filter := queue.Filter{Batch: batchName, CreatedOn: businessDate}
+	var results []string
+	for _, state := range []string{queue.StateSent, queue.StateDeadLetter, queue.StatePending} {
+		notifications, err := outbox.List(state, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list %s notifications: %v", state, err)
+		}
+		for _, n := range notifications {
+			results = append(results, n.ResultRow(cfg.ENCB.ResultExtras))
+		}
+		runs.FromContext(ctx).Add(deliveryOutcome(state), len(notifications))
+	}
+	if len(results) == 0 {
+		log.Info("No e-NCB notifications to report.")
+		return nil
+	}
+
+	localDir := cfg.ENCB.FTP.LocalPath
+	if _, err := os.Stat(localDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return fmt.Errorf("failed to create local directory '%s': %v", localDir, err)
+		}
+	}
+
+	resultFileName := opts.ResultFileName(cfg.ENCB.ResultPrefix + "_delivery")
+	resultFilePath := filepath.Join(localDir, resultFileName)
+	if err := util.WriteResultToFile(resultFilePath, results); err != nil {
+		return fmt.Errorf("failed to write result to file '%s': %v", resultFilePath, err)
+	}
+	log.Infof("Wrote %d delivery results to file '%s'", len(results), resultFilePath)
+
+	ftpClient, err := ftp.NewClient(ctx, ftp.Config{
+		Host:     cfg.ENCB.FTP.Host,
+		User:     cfg.ENCB.FTP.User,
+		Password: cfg.ENCB.FTP.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create FTP client for e-NCB: %v", err)
+	}
+	defer ftpClient.Close()
+
+	remoteResultPath := cfg.ENCB.FTP.RemotePathResult
+	err = ftpClient.UploadFile(ctx, resultFilePath, filepath.Join(remoteResultPath, resultFileName))
+	if err != nil {
+		return fmt.Errorf("failed to upload result file '%s' to '%s': %v", resultFilePath, remoteResultPath, err)
+	}
+	log.Infof("Uploaded result file '%s' to '%s'", resultFilePath, remoteResultPath)
+	os.Remove(resultFilePath)
+	return nil
 }
@@ -2,14 +2,20 @@ package encb
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"notification_batch/internal/api"
+	"notification_batch/internal/checkpoint"
 	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
 	"notification_batch/internal/logger"
 	"notification_batch/internal/model"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/topic"
 	"notification_batch/internal/util"
 )
 
@@ -27,51 +33,68 @@ const (
 	encbMessageInboxENLength = 200
 )
 
-// ProcessENCBFile reads and processes each line of the e-NCB file.
-func ProcessENCBFile(cfg *config.Config, filePath string) ([]string, error) {
+// recordFields lists the record fields that topic rules may match on.
+var recordFields = []string{"user_token", "title_inbox_th", "title_inbox_en"}
+
+// ValidateConfig checks the e-NCB batch configuration at startup.
+func ValidateConfig(cfg *config.Config) error {
+	if err := topic.Validate(cfg.ENCB.Topic, recordFields); err != nil {
+		return fmt.Errorf("e_ncb: %v", err)
+	}
+	return nil
+}
+
+// ProcessENCBFile reads each line of the e-NCB file and enqueues a notification for the dispatcher.
+// Each record gets its own correlation ID, derived from ctx, which is logged, stored with the
+// queued notification and written to the result row.
+// Progress is checkpointed after every record; when ctx is cancelled processing stops after
+// the current record and returns ctx.Err(), and the next run resumes from the checkpoint.
+func ProcessENCBFile(ctx context.Context, cfg *config.Config, filePath string) ([]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file '%s': %v", filePath, err)
 	}
 	defer file.Close()
 
-	var results []string
+	outbox, err := queue.Open(queue.OutboxPath(cfg.DataPath))
+	if err != nil {
+		return nil, err
+	}
+
+	cp, err := checkpoint.Open(checkpointPath(cfg), filePath)
+	if err != nil {
+		return nil, err
+	}
+	lastLine, results, err := cp.Load()
+	if err != nil {
+		return nil, err
+	}
+	if lastLine > 0 {
+		logger.FromContext(ctx).Infof("Resuming file '%s' after line %d", filePath, lastLine)
+	}
+
+	sourceFile := filepath.Base(filePath)
+	recorder := runs.FromContext(ctx)
+	lineNo := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) < encbUserTokenStart+encbUserTokenLength {
-			logger.AppLogger.Sugar().Warnf("Skipping line due to insufficient length: '%s'", line)
+		lineNo++
+		if lineNo <= lastLine {
 			continue
 		}
-
-		userToken := strings.TrimSpace(util.SafeSubstring(line, encbUserTokenStart, encbUserTokenLength))
-		titleInboxTH := strings.TrimSpace(util.SafeSubstring(line, encbTitleInboxTHStart, encbTitleInboxTHLength))
-		messageInboxTH := strings.TrimSpace(util.SafeSubstring(line, encbMessageInboxTHStart, encbMessageInboxTHLength))
-		titleInboxEN := strings.TrimSpace(util.SafeSubstring(line, encbTitleInboxENStart, encbTitleInboxENLength))
-		messageInboxEN := strings.TrimSpace(util.SafeSubstring(line, encbMessageInboxENStart, encbMessageInboxENLength))
-
-		notificationRequest := model.NotificationRequest{
-			Usertoken:      userToken,
-			Topiccode:      "test",
-			TitleTH:        titleInboxTH,
-			MessageTH:      messageInboxTH,
-			TitleinboxTH:   titleInboxTH,
-			MessageinboxTH: messageInboxTH,
-			TitleEN:        titleInboxEN,
-			MessageEN:      messageInboxEN,
-			TitleinboxEN:   titleInboxEN,
-			MessageinboxEN: messageInboxEN,
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		notificationClient := api.NewNotificationClient(cfg)
-		notificationResponse, err := notificationClient.SendNotification(notificationRequest)
-		if err != nil {
-			logger.AppLogger.Sugar().Errorf("Failed to call Send Notification API (TH) for user token '%s': %v", userToken, err)
-		} else {
-			logger.AppLogger.Sugar().Infof("Notification (TH) sent for user token '%s', Response: %+v", userToken, notificationResponse)
-			results = append(results, fmt.Sprintf("%s,%s,%s,%s,%+v", userToken, titleInboxTH, messageInboxTH, "TH", notificationResponse))
+		row, rec := processENCBLine(ctx, cfg, outbox, sourceFile, scanner.Text())
+		if row != "" {
+			results = append(results, row)
 		}
-
+		if err := cp.Record(lineNo, row); err != nil {
+			return nil, err
+		}
+		rec.File, rec.Line = sourceFile, lineNo
+		recorder.Record(rec)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -80,3 +103,59 @@ func ProcessENCBFile(cfg *config.Config, filePath string) ([]string, error) {
 
 	return results, nil
 }
+
+// processENCBLine enqueues the notification for a single record and returns its result row,
+// or "" if none is produced, and its outcome for the run history.
+func processENCBLine(ctx context.Context, cfg *config.Config, outbox *queue.Store, sourceFile, line string) (string, runs.Record) {
+	if len(line) < encbUserTokenStart+encbUserTokenLength {
+		logger.FromContext(ctx).Warnw("Skipping line due to insufficient length", "line_length", len(line))
+		return "", runs.Record{Outcome: runs.OutcomeSkipped, Detail: "insufficient length"}
+	}
+
+	userToken := strings.TrimSpace(util.SafeSubstring(line, encbUserTokenStart, encbUserTokenLength))
+	titleInboxTH := strings.TrimSpace(util.SafeSubstring(line, encbTitleInboxTHStart, encbTitleInboxTHLength))
+	messageInboxTH := strings.TrimSpace(util.SafeSubstring(line, encbMessageInboxTHStart, encbMessageInboxTHLength))
+	titleInboxEN := strings.TrimSpace(util.SafeSubstring(line, encbTitleInboxENStart, encbTitleInboxENLength))
+	messageInboxEN := strings.TrimSpace(util.SafeSubstring(line, encbMessageInboxENStart, encbMessageInboxENLength))
+	correlationID := util.GenerateRequestID()
+	log := logger.FromContext(correlation.WithCorrelationID(ctx, correlationID))
+
+	topicCode := topic.Resolve(cfg.ENCB.Topic, map[string]string{
+		"user_token":     userToken,
+		"title_inbox_th": titleInboxTH,
+		"title_inbox_en": titleInboxEN,
+	})
+
+	notificationRequest := model.NotificationRequest{
+		Usertoken:      userToken,
+		Topiccode:      topicCode,
+		TitleTH:        titleInboxTH,
+		MessageTH:      messageInboxTH,
+		TitleinboxTH:   titleInboxTH,
+		MessageinboxTH: messageInboxTH,
+		TitleEN:        titleInboxEN,
+		MessageEN:      messageInboxEN,
+		TitleinboxEN:   titleInboxEN,
+		MessageinboxEN: messageInboxEN,
+	}
+
+	notification := &queue.Notification{
+		Batch:         batchName,
+		RunID:         correlation.RunID(ctx),
+		CorrelationID: correlationID,
+		SourceFile:    sourceFile,
+		ResultColumns: []string{userToken, titleInboxTH, messageInboxTH, "TH"},
+		Request:       notificationRequest,
+	}
+	if err := outbox.Enqueue(notification); err != nil {
+		log.Errorw("Failed to enqueue notification", "user_token", userToken, "error", err)
+		return "", runs.Record{Outcome: runs.OutcomeFailed, Detail: err.Error(), CorrelationID: correlationID}
+	}
+	log.Infow("Notification queued", "id", notification.ID, "user_token", userToken)
+	row := fmt.Sprintf("%s,%s,%s,%s,Queued %s", userToken, titleInboxTH, messageInboxTH, "TH", notification.ID)
+	if cfg.ENCB.ResultExtras {
+		row += "," + correlationID
+	}
+	return row,
+		runs.Record{Outcome: runs.OutcomeQueued, CorrelationID: correlationID, NotificationID: notification.ID}
+}
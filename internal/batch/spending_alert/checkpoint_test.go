@@ -0,0 +1,154 @@
+package spending_alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// alertSettingServer answers every Get Alert Setting call as eligible. The first call for
+// cancelToken runs cancel and, if block is set, waits for the request to be cancelled
+// instead of answering.
+func alertSettingServer(t *testing.T, cancelToken string, cancel func(), block bool) *httptest.Server {
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req model.AlertSettingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.UserToken == cancelToken {
+			first := false
+			once.Do(func() { first = true })
+			if first {
+				cancel()
+				if block {
+					<-r.Context().Done()
+					return
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(model.AlertSettingResponse{
+			ResponseCode:      "0000",
+			UserToken:         req.UserToken,
+			SpendingAlertFlag: true,
+			LastLogin:         util.Now().Format(defaultLastLoginLayout),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// lateCancelContext reports cancellation through Err without closing Done, so an API call
+// in flight still completes and the record is interrupted between the call and the enqueue.
+type lateCancelContext struct {
+	context.Context
+	cancelled atomic.Bool
+}
+
+func (c *lateCancelContext) Err() error {
+	if c.cancelled.Load() {
+		return context.Canceled
+	}
+	return nil
+}
+
+func processorTestConfig(t *testing.T, apiURL string) *config.Config {
+	cfg := &config.Config{
+		DataPath:     t.TempDir(),
+		LogPath:      t.TempDir(),
+		APILogPrefix: "api",
+		TemplateFile: "../../../config/templates.yaml",
+	}
+	cfg.APIEndpoints.GetAlertSetting = apiURL
+	cfg.APIEndpoints.Timeout = 5
+	cfg.APIEndpoints.AlertSettingMaxAttempts = 1
+	cfg.APIEndpoints.ResponseCodes.AlertSetting.Success = []string{"0000"}
+	cfg.SpendingAlert.Topic.Default = "SPENDING_ALERT"
+	cfg.SpendingAlert.DefaultLanguage = languageTH
+	cfg.SpendingAlert.Eligibility.LastLoginWithinDays = 90
+	cfg.Masking.CardNoFormat = util.CardMaskLast4
+	return cfg
+}
+
+func TestProcessSpendingAlertFileResumesAfterCancel(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	tokens := []string{"token-0001", "token-0002", "token-0003"}
+
+	var lines []string
+	for _, token := range tokens {
+		line := []byte(testLine(""))
+		copy(line[userTokenStart:], strings.Repeat(" ", userTokenLength))
+		copy(line[userTokenStart:], token)
+		lines = append(lines, string(line))
+	}
+
+	tests := []struct {
+		name    string
+		block   bool
+		context func() (context.Context, func())
+	}{
+		{"cancelled during the API call", true, func() (context.Context, func()) {
+			return context.WithCancel(context.Background())
+		}},
+		{"cancelled after the API answered", false, func() (context.Context, func()) {
+			ctx := &lateCancelContext{Context: context.Background()}
+			return ctx, func() { ctx.cancelled.Store(true) }
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "sa_20261019.txt")
+			if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := tt.context()
+			defer cancel()
+			server := alertSettingServer(t, tokens[1], cancel, tt.block)
+			cfg := processorTestConfig(t, server.URL)
+
+			if _, err := ProcessSpendingAlertFile(ctx, cfg, file); err != context.Canceled {
+				t.Fatalf("ProcessSpendingAlertFile() error = %v, want context.Canceled", err)
+			}
+
+			results, err := ProcessSpendingAlertFile(context.Background(), cfg, file)
+			if err != nil {
+				t.Fatalf("resumed ProcessSpendingAlertFile() error = %v", err)
+			}
+			if len(results) != len(tokens) {
+				t.Errorf("results = %d rows, want %d: %v", len(results), len(tokens), results)
+			}
+
+			outbox, err := queue.Open(queue.OutboxPath(cfg.DataPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pending, err := outbox.List(queue.StatePending, queue.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			queued := make(map[string]int)
+			for _, n := range pending {
+				queued[n.Request.Usertoken]++
+			}
+			for _, token := range tokens {
+				if queued[token] != 1 {
+					t.Errorf("%s queued %d times, want once", token, queued[token])
+				}
+			}
+		})
+	}
+}
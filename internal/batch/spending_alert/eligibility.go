@@ -0,0 +1,152 @@
+package spending_alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+	"notification_batch/internal/util"
+)
+
+// Eligibility rule names recorded in results and logs.
+const (
+	ruleSpendingAlertFlag  = "spending_alert_flag"
+	ruleLastLogin          = "last_login_within_days"
+	ruleMinAmount          = "min_amount"
+	ruleMerchantCategory   = "excluded_merchant_category"
+	ruleCustomerThreshold  = "customer_threshold"
+	ruleAllMatched         = "eligible"
+	ruleAlertSetting       = "alert_setting"
+	ruleMessageTemplate    = "message_template"
+	ruleOutbox             = "outbox"
+	defaultLastLoginLayout = "2006-01-02 15:04:05"
+)
+
+// eligibilityInput is the data a rule is evaluated against.
+type eligibilityInput struct {
+	record  spendingAlertRecord
+	setting *model.AlertSettingResponse
+	now     time.Time
+}
+
+// eligibilityRule is a single named check; check returns false when the record is not eligible.
+type eligibilityRule struct {
+	name  string
+	check func(in eligibilityInput) bool
+}
+
+// eligibilityResult reports whether a record is eligible and which rule decided it.
+type eligibilityResult struct {
+	Eligible bool
+	Rule     string
+}
+
+// newEligibilityRules builds the rule set from configuration, skipping rules that are disabled.
+func newEligibilityRules(cfg config.EligibilityConfig) []eligibilityRule {
+	layout := cfg.LastLoginLayout
+	if layout == "" {
+		layout = defaultLastLoginLayout
+	}
+	days := cfg.LastLoginWithinDays
+	rules := []eligibilityRule{{
+		name:  ruleSpendingAlertFlag,
+		check: func(in eligibilityInput) bool { return in.setting.SpendingAlertFlag },
+	}, {
+		name: ruleLastLogin,
+		check: func(in eligibilityInput) bool {
+			return isLastLoginWithin(in.setting.LastLogin, layout, days, in.now)
+		},
+	}}
+
+	if cfg.MinAmount > 0 {
+		minAmount := cfg.MinAmount
+		rules = append(rules, eligibilityRule{
+			name: ruleMinAmount,
+			check: func(in eligibilityInput) bool {
+				amount, ok := parseAmount(in.record.Amount)
+				return ok && amount >= minAmount
+			},
+		})
+	}
+
+	if len(cfg.ExcludedMerchantCategories) > 0 {
+		excluded := make(map[string]bool, len(cfg.ExcludedMerchantCategories))
+		for _, mcc := range cfg.ExcludedMerchantCategories {
+			excluded[strings.TrimSpace(mcc)] = true
+		}
+		rules = append(rules, eligibilityRule{
+			name:  ruleMerchantCategory,
+			check: func(in eligibilityInput) bool { return !excluded[in.record.MerchantCategory] },
+		})
+	}
+
+	if cfg.UseCustomerThreshold {
+		rules = append(rules, eligibilityRule{
+			name: ruleCustomerThreshold,
+			check: func(in eligibilityInput) bool {
+				if in.setting.SpendingAlertThreshold <= 0 {
+					return true
+				}
+				amount, ok := parseAmount(in.record.Amount)
+				return ok && amount >= in.setting.SpendingAlertThreshold
+			},
+		})
+	}
+
+	return rules
+}
+
+// evaluateEligibility runs the rules in order and stops at the first one that fails.
+func evaluateEligibility(rules []eligibilityRule, in eligibilityInput) eligibilityResult {
+	for _, rule := range rules {
+		if !rule.check(in) {
+			return eligibilityResult{Eligible: false, Rule: rule.name}
+		}
+	}
+	return eligibilityResult{Eligible: true, Rule: ruleAllMatched}
+}
+
+func isLastLoginWithin(lastLogin, layout string, days int, now time.Time) bool {
+	if lastLogin == "" {
+		return false
+	}
+	parsedTime, err := time.ParseInLocation(layout, lastLogin, util.Location())
+	if err != nil {
+		logger.AppLogger.Sugar().Warnf("Failed to parse last login time '%s': %v", lastLogin, err)
+		return false
+	}
+	return parsedTime.After(now.AddDate(0, 0, -days))
+}
+
+func parseAmount(s string) (float64, bool) {
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+// validateEligibility checks the eligibility configuration at startup.
+func validateEligibility(cfg config.EligibilityConfig, fields []string) error {
+	if cfg.LastLoginWithinDays <= 0 {
+		return fmt.Errorf("eligibility.last_login_within_days must be positive, got %d", cfg.LastLoginWithinDays)
+	}
+	if cfg.MinAmount < 0 {
+		return fmt.Errorf("eligibility.min_amount must not be negative")
+	}
+	available := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		available[f] = true
+	}
+	if (cfg.MinAmount > 0 || cfg.UseCustomerThreshold) && !available[fieldAmount] {
+		return fmt.Errorf("eligibility: min_amount and use_customer_threshold need record_fields.%s", fieldAmount)
+	}
+	if len(cfg.ExcludedMerchantCategories) > 0 && !available[fieldMerchantCategory] {
+		return fmt.Errorf("eligibility: excluded_merchant_categories needs record_fields.%s", fieldMerchantCategory)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package spending_alert
+
+import (
+	"testing"
+	"time"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+func TestEvaluateEligibility(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, util.Location())
+	recentLogin := now.AddDate(0, 0, -1).Format(defaultLastLoginLayout)
+	all := config.EligibilityConfig{
+		LastLoginWithinDays:        30,
+		MinAmount:                  500,
+		ExcludedMerchantCategories: []string{"7995", " 6011 "},
+		UseCustomerThreshold:       true,
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.EligibilityConfig
+		record  spendingAlertRecord
+		setting model.AlertSettingResponse
+		want    eligibilityResult
+	}{
+		{
+			name:    "alert switched off",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "1000"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: false, LastLogin: recentLogin},
+			want:    eligibilityResult{Rule: ruleSpendingAlertFlag},
+		},
+		{
+			name:    "last login just inside the window",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: now.AddDate(0, 0, -30).Add(time.Second).Format(defaultLastLoginLayout)},
+			want:    eligibilityResult{Eligible: true, Rule: ruleAllMatched},
+		},
+		{
+			name:    "last login exactly at the window start",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: now.AddDate(0, 0, -30).Format(defaultLastLoginLayout)},
+			want:    eligibilityResult{Rule: ruleLastLogin},
+		},
+		{
+			name:    "last login missing",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true},
+			want:    eligibilityResult{Rule: ruleLastLogin},
+		},
+		{
+			name:    "last login unparsable",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: "19/10/2026"},
+			want:    eligibilityResult{Rule: ruleLastLogin},
+		},
+		{
+			name:    "amount below minimum",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "499.99"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Rule: ruleMinAmount},
+		},
+		{
+			name:    "amount equal to minimum",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30, MinAmount: 500},
+			record:  spendingAlertRecord{Amount: "500.00"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Eligible: true, Rule: ruleAllMatched},
+		},
+		{
+			name:    "amount with thousands separator",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30, MinAmount: 1000},
+			record:  spendingAlertRecord{Amount: "1,250.00"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Eligible: true, Rule: ruleAllMatched},
+		},
+		{
+			name:    "amount unparsable",
+			cfg:     config.EligibilityConfig{LastLoginWithinDays: 30, MinAmount: 1},
+			record:  spendingAlertRecord{Amount: "N/A"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Rule: ruleMinAmount},
+		},
+		{
+			name:    "excluded merchant category",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "1000", MerchantCategory: "6011"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Rule: ruleMerchantCategory},
+		},
+		{
+			name:    "below the customer threshold",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "1000", MerchantCategory: "5411"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin, SpendingAlertThreshold: 1500},
+			want:    eligibilityResult{Rule: ruleCustomerThreshold},
+		},
+		{
+			name:    "at the customer threshold",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "1500", MerchantCategory: "5411"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin, SpendingAlertThreshold: 1500},
+			want:    eligibilityResult{Eligible: true, Rule: ruleAllMatched},
+		},
+		{
+			name:    "no customer threshold set",
+			cfg:     all,
+			record:  spendingAlertRecord{Amount: "600", MerchantCategory: "5411"},
+			setting: model.AlertSettingResponse{SpendingAlertFlag: true, LastLogin: recentLogin},
+			want:    eligibilityResult{Eligible: true, Rule: ruleAllMatched},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := tt.setting
+			got := evaluateEligibility(newEligibilityRules(tt.cfg), eligibilityInput{record: tt.record, setting: &setting, now: now})
+			if got != tt.want {
+				t.Errorf("evaluateEligibility() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
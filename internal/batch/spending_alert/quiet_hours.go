@@ -0,0 +1,106 @@
+package spending_alert
+
+import (
+	"fmt"
+	"time"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+)
+
+// ruleQuietHours is recorded in results for notifications deferred by quiet hours.
+const ruleQuietHours = "quiet_hours"
+
+// quietHours is a daily window, in minutes since midnight, that may span midnight.
+type quietHours struct {
+	start int
+	end   int
+}
+
+// parseQuietHours parses a quiet hours window; ok is false when the window is not configured.
+func parseQuietHours(cfg config.QuietHoursConfig) (qh quietHours, ok bool, err error) {
+	if cfg.Start == "" && cfg.End == "" {
+		return quietHours{}, false, nil
+	}
+	start, err := parseClock(cfg.Start)
+	if err != nil {
+		return quietHours{}, false, fmt.Errorf("invalid quiet_hours.start: %v", err)
+	}
+	end, err := parseClock(cfg.End)
+	if err != nil {
+		return quietHours{}, false, fmt.Errorf("invalid quiet_hours.end: %v", err)
+	}
+	if start == end {
+		return quietHours{}, false, fmt.Errorf("quiet_hours.start and quiet_hours.end must differ")
+	}
+	return quietHours{start: start, end: end}, true, nil
+}
+
+// contains reports whether t falls inside the quiet hours window.
+func (q quietHours) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// nextAllowed returns the first time at or after t that is outside the quiet hours window.
+func (q quietHours) nextAllowed(t time.Time) time.Time {
+	if !q.contains(t) {
+		return t
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// quietHoursFor returns the windows that apply to a customer: their own window from their alert
+// settings when they set one, which replaces the batch window, otherwise the batch window.
+func quietHoursFor(batch []quietHours, setting *model.AlertSettingResponse) []quietHours {
+	if own := customerQuietHours(setting); own != nil {
+		return own
+	}
+	return batch
+}
+
+// customerQuietHours returns the customer's own quiet hours window from their alert settings, if any.
+func customerQuietHours(setting *model.AlertSettingResponse) []quietHours {
+	qh, ok, err := parseQuietHours(config.QuietHoursConfig{Start: setting.QuietHoursStart, End: setting.QuietHoursEnd})
+	if err != nil {
+		logger.AppLogger.Sugar().Warnw("Ignoring invalid customer quiet hours", "user_token", setting.UserToken, "error", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return []quietHours{qh}
+}
+
+// nextAllowedTime returns the first time at or after t that is outside every window.
+func nextAllowedTime(t time.Time, windows []quietHours) time.Time {
+	for i := 0; i <= 2*len(windows); i++ {
+		moved := false
+		for _, w := range windows {
+			if w.contains(t) {
+				t = w.nextAllowed(t)
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return t
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not in HH:MM format", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,108 @@
+package spending_alert
+
+import (
+	"testing"
+	"time"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+
+	"go.uber.org/zap"
+)
+
+func clock(day int, hhmm string) time.Time {
+	t, _ := time.Parse("15:04", hhmm)
+	return time.Date(2026, 10, day, t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func mustQuietHours(t *testing.T, start, end string) quietHours {
+	t.Helper()
+	qh, ok, err := parseQuietHours(config.QuietHoursConfig{Start: start, End: end})
+	if err != nil || !ok {
+		t.Fatalf("parseQuietHours(%s, %s) = %v, %v", start, end, ok, err)
+	}
+	return qh
+}
+
+func TestQuietHours(t *testing.T) {
+	overnight := mustQuietHours(t, "22:00", "07:00")
+	daytime := mustQuietHours(t, "12:00", "13:30")
+
+	tests := []struct {
+		name     string
+		window   quietHours
+		at       time.Time
+		contains bool
+		next     time.Time
+	}{
+		{"overnight, before start", overnight, clock(19, "21:59"), false, clock(19, "21:59")},
+		{"overnight, at start", overnight, clock(19, "22:00"), true, clock(20, "07:00")},
+		{"overnight, before midnight", overnight, clock(19, "23:45"), true, clock(20, "07:00")},
+		{"overnight, after midnight", overnight, clock(20, "03:10"), true, clock(20, "07:00")},
+		{"overnight, last minute", overnight, clock(20, "06:59"), true, clock(20, "07:00")},
+		{"overnight, at end", overnight, clock(20, "07:00"), false, clock(20, "07:00")},
+		{"daytime, at start", daytime, clock(19, "12:00"), true, clock(19, "13:30")},
+		{"daytime, at end", daytime, clock(19, "13:30"), false, clock(19, "13:30")},
+		{"daytime, outside", daytime, clock(19, "23:00"), false, clock(19, "23:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.at); got != tt.contains {
+				t.Errorf("contains(%s) = %v, want %v", tt.at, got, tt.contains)
+			}
+			if got := tt.window.nextAllowed(tt.at); !got.Equal(tt.next) {
+				t.Errorf("nextAllowed(%s) = %s, want %s", tt.at, got, tt.next)
+			}
+		})
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		start, end string
+		ok         bool
+		wantErr    bool
+	}{
+		{"", "", false, false},
+		{"22:00", "07:00", true, false},
+		{"22:00", "", false, true},
+		{"25:00", "07:00", false, true},
+		{"07:00", "07:00", false, true},
+	}
+	for _, tt := range tests {
+		_, ok, err := parseQuietHours(config.QuietHoursConfig{Start: tt.start, End: tt.end})
+		if ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("parseQuietHours(%q, %q) = %v, %v", tt.start, tt.end, ok, err)
+		}
+	}
+}
+
+func TestNextAllowedTime(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	overnight := []quietHours{mustQuietHours(t, "22:00", "07:00")}
+
+	tests := []struct {
+		name    string
+		batch   []quietHours
+		setting model.AlertSettingResponse
+		at      time.Time
+		want    time.Time
+	}{
+		{"batch window applies", overnight, model.AlertSettingResponse{}, clock(19, "23:00"), clock(20, "07:00")},
+		{"outside the batch window", overnight, model.AlertSettingResponse{}, clock(20, "08:00"), clock(20, "08:00")},
+		{"customer window replaces the batch window", overnight, model.AlertSettingResponse{QuietHoursStart: "23:30", QuietHoursEnd: "06:00"}, clock(19, "23:00"), clock(19, "23:00")},
+		{"inside the customer window", overnight, model.AlertSettingResponse{QuietHoursStart: "23:30", QuietHoursEnd: "06:00"}, clock(20, "01:00"), clock(20, "06:00")},
+		{"customer daytime window", overnight, model.AlertSettingResponse{QuietHoursStart: "08:00", QuietHoursEnd: "18:00"}, clock(20, "09:00"), clock(20, "18:00")},
+		{"invalid customer window falls back to the batch window", overnight, model.AlertSettingResponse{QuietHoursStart: "late", QuietHoursEnd: "06:00"}, clock(19, "23:00"), clock(20, "07:00")},
+		{"customer window without a batch window", nil, model.AlertSettingResponse{QuietHoursStart: "23:30", QuietHoursEnd: "06:00"}, clock(20, "05:59"), clock(20, "06:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := tt.setting
+			if got := nextAllowedTime(tt.at, quietHoursFor(tt.batch, &setting)); !got.Equal(tt.want) {
+				t.Errorf("nextAllowedTime(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,15 +2,24 @@ package spending_alert
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"notification_batch/internal/api"
+	"notification_batch/internal/checkpoint"
 	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
 	"notification_batch/internal/logger"
 	"notification_batch/internal/model"
+	"notification_batch/internal/msgtemplate"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/topic"
 	"notification_batch/internal/util"
 )
 
@@ -26,61 +35,213 @@ const (
 	originalTimeLength = 8
 )
 
-// ProcessSpendingAlertFile reads and processes each line of the Spending Alert file.
-func ProcessSpendingAlertFile(cfg *config.Config, filePath string) ([]string, error) {
+// Record fields whose positions are not fixed by the file format and must be configured
+// in spending_alert.record_fields before rules can use them.
+const (
+	fieldAmount           = "amount"
+	fieldMerchant         = "merchant"
+	fieldChannel          = "channel"
+	fieldMerchantCategory = "merchant_category"
+)
+
+// fixedFields lists the record fields that are always available to topic rules.
+var fixedFields = []string{"user_token", "date", "time"}
+
+// configurableFields lists the record fields that may be located through record_fields.
+var configurableFields = []string{fieldAmount, fieldMerchant, fieldChannel, fieldMerchantCategory}
+
+// templateTopic is the message template topic used for Spending Alert notifications.
+const templateTopic = "spending_alert"
+
+// Supported notification languages and delivery channels.
+const (
+	languageTH   = "th"
+	languageEN   = "en"
+	channelPush  = "push"
+	channelInbox = "inbox"
+)
+
+// spendingAlertRecord holds the fields parsed from a single Spending Alert file line.
+// Its exported fields are the variables available to message templates.
+type spendingAlertRecord struct {
+	CardNo           string
+	UserToken        string
+	Date             string
+	Time             string
+	Amount           string
+	Merchant         string
+	Channel          string
+	MerchantCategory string
+}
+
+// fields returns the record values keyed by field name.
+func (r spendingAlertRecord) fields() map[string]string {
+	return map[string]string{
+		"user_token":          r.UserToken,
+		"date":                r.Date,
+		"time":                r.Time,
+		fieldAmount:           r.Amount,
+		fieldMerchant:         r.Merchant,
+		fieldChannel:          r.Channel,
+		fieldMerchantCategory: r.MerchantCategory,
+	}
+}
+
+// parseSpendingAlertRecord extracts the fields from a line, masking the card number.
+// Configurable fields missing from layout are left empty.
+func parseSpendingAlertRecord(line, cardMaskFormat string, layout map[string]config.FieldPosition) spendingAlertRecord {
+	field := func(name string) string {
+		pos, ok := layout[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(util.SafeSubstring(line, pos.Start, pos.Length))
+	}
+	return spendingAlertRecord{
+		CardNo:           util.MaskCardNo(strings.TrimSpace(util.SafeSubstring(line, cardNoStart, cardNoLength)), cardMaskFormat),
+		UserToken:        strings.TrimSpace(util.SafeSubstring(line, userTokenStart, userTokenLength)),
+		Date:             strings.TrimSpace(util.SafeSubstring(line, originalDateStart, originalDateLength)),
+		Time:             strings.TrimSpace(util.SafeSubstring(line, originalTimeStart, originalTimeLength)),
+		Amount:           field(fieldAmount),
+		Merchant:         field(fieldMerchant),
+		Channel:          field(fieldChannel),
+		MerchantCategory: field(fieldMerchantCategory),
+	}
+}
+
+// availableFields returns the record fields rules may use: the fixed ones plus those located in layout.
+func availableFields(layout map[string]config.FieldPosition) ([]string, error) {
+	configurable := make(map[string]bool, len(configurableFields))
+	for _, name := range configurableFields {
+		configurable[name] = true
+	}
+	for name, pos := range layout {
+		if !configurable[name] {
+			return nil, fmt.Errorf("record_fields: unknown field '%s' (configurable: %s)", name, strings.Join(configurableFields, ", "))
+		}
+		if pos.Start < 0 || pos.Length <= 0 {
+			return nil, fmt.Errorf("record_fields.%s: start must not be negative and length must be positive", name)
+		}
+	}
+	fields := append([]string(nil), fixedFields...)
+	for _, name := range configurableFields {
+		if _, ok := layout[name]; ok {
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// ValidateConfig checks the Spending Alert batch configuration at startup.
+func ValidateConfig(cfg *config.Config) error {
+	fields, err := availableFields(cfg.SpendingAlert.RecordFields)
+	if err != nil {
+		return fmt.Errorf("spending_alert: %v", err)
+	}
+	if err := topic.Validate(cfg.SpendingAlert.Topic, fields); err != nil {
+		return fmt.Errorf("spending_alert: %v", err)
+	}
+	if !isSupportedLanguage(cfg.SpendingAlert.DefaultLanguage) {
+		return fmt.Errorf("spending_alert: default_language must be '%s' or '%s', got '%s'", languageTH, languageEN, cfg.SpendingAlert.DefaultLanguage)
+	}
+	if err := validateEligibility(cfg.SpendingAlert.Eligibility, fields); err != nil {
+		return fmt.Errorf("spending_alert: %v", err)
+	}
+	if _, _, err := parseQuietHours(cfg.SpendingAlert.QuietHours); err != nil {
+		return fmt.Errorf("spending_alert: %v", err)
+	}
+	return nil
+}
+
+// fileProcessor holds the per-file state used to process Spending Alert records.
+type fileProcessor struct {
+	cfg                *config.Config
+	sourceFile         string
+	templates          *msgtemplate.Store
+	rules              []eligibilityRule
+	globalQuietHours   []quietHours
+	outbox             *queue.Store
+	alertSettingClient *api.AlertSettingClient
+}
+
+// ProcessSpendingAlertFile reads each line of the Spending Alert file and enqueues eligible
+// notifications for the dispatcher; it does not wait for them to be sent.
+// Each record gets its own correlation ID, derived from ctx, which is sent to the APIs,
+// logged, stored with the queued notification and written to the result row.
+// Progress is checkpointed after every record; when ctx is cancelled processing stops after
+// the current record and returns ctx.Err(), and the next run resumes from the checkpoint.
+// A record abandoned before its notification was queued is left for the resumed run; one
+// that was queued is always checkpointed so it is not sent twice.
+func ProcessSpendingAlertFile(ctx context.Context, cfg *config.Config, filePath string) ([]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file '%s': %v", filePath, err)
 	}
 	defer file.Close()
 
-	var results []string
+	templates, err := msgtemplate.Get(cfg.TemplateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load message templates: %v", err)
+	}
+
+	var globalQuietHours []quietHours
+	if qh, ok, err := parseQuietHours(cfg.SpendingAlert.QuietHours); err != nil {
+		return nil, err
+	} else if ok {
+		globalQuietHours = append(globalQuietHours, qh)
+	}
+
+	outbox, err := queue.Open(queue.OutboxPath(cfg.DataPath))
+	if err != nil {
+		return nil, err
+	}
+
+	cp, err := checkpoint.Open(checkpointPath(cfg), filePath)
+	if err != nil {
+		return nil, err
+	}
+	lastLine, results, err := cp.Load()
+	if err != nil {
+		return nil, err
+	}
+	if lastLine > 0 {
+		logger.FromContext(ctx).Infof("Resuming file '%s' after line %d", filePath, lastLine)
+	}
+
+	p := &fileProcessor{
+		cfg:                cfg,
+		sourceFile:         filepath.Base(filePath),
+		templates:          templates,
+		rules:              newEligibilityRules(cfg.SpendingAlert.Eligibility),
+		globalQuietHours:   globalQuietHours,
+		outbox:             outbox,
+		alertSettingClient: api.NewAlertSettingClient(cfg),
+	}
+
+	recorder := runs.FromContext(ctx)
+	lineNo := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) < userTokenStart+userTokenLength {
-			logger.AppLogger.Sugar().Warnf("Skipping line due to insufficient length: '%s'", line)
+		lineNo++
+		if lineNo <= lastLine {
 			continue
 		}
-
-		cardNo := strings.TrimSpace(util.SafeSubstring(line, cardNoStart, cardNoLength))
-		userToken := strings.TrimSpace(util.SafeSubstring(line, userTokenStart, userTokenLength))
-		originalDateStr := strings.TrimSpace(util.SafeSubstring(line, originalDateStart, originalDateLength))
-		originalTimeStr := strings.TrimSpace(util.SafeSubstring(line, originalTimeStart, originalTimeLength))
-
-		alertSettingClient := api.NewAlertSettingClient(cfg)
-		alertSettingResponse, err := alertSettingClient.GetAlertSetting(userToken)
-		if err != nil {
-			logger.AppLogger.Sugar().Errorf("Failed to call Get Alert Setting API for user token '%s': %v", userToken, err)
-			continue
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		if alertSettingResponse.SpendingAlertFlag && isLastLoginWithin90Days(alertSettingResponse.LastLogin) {
-			notificationRequest := model.NotificationRequest{
-				Usertoken:      userToken,
-				Topiccode:      "test",
-				TitleTH:        "แจ้งเตือนการใช้จ่าย",
-				MessageTH:      fmt.Sprintf("คุณมีการใช้จ่ายผ่านบัตร %s เมื่อวันที่ %s เวลา %s", cardNo, originalDateStr, originalTimeStr),
-				TitleEN:        "Spending Alert",
-				MessageEN:      fmt.Sprintf("You have a spending transaction with card %s on %s at %s", cardNo, originalDateStr, originalTimeStr),
-				TitleinboxTH:   "แจ้งเตือนการใช้จ่าย",
-				MessageinboxTH: fmt.Sprintf("คุณมีการใช้จ่ายผ่านบัตร %s เมื่อวันที่ %s เวลา %s", cardNo, originalDateStr, originalTimeStr),
-				TitleinboxEN:   "Spending Alert",
-				MessageinboxEN: fmt.Sprintf("You have a spending transaction with card %s on %s at %s", cardNo, originalDateStr, originalTimeStr),
-			}
-
-			notificationClient := api.NewNotificationClient(cfg)
-			notificationResponse, err := notificationClient.SendNotification(notificationRequest)
-			if err != nil {
-				logger.AppLogger.Sugar().Errorf("Failed to call Send Notification API for user token '%s': %v", userToken, err)
-			} else {
-				logger.AppLogger.Sugar().Infof("Notification sent for user token '%s', Response: %+v", userToken, notificationResponse)
-				results = append(results, fmt.Sprintf("%s,%s,%s,%s,%s,%+v", cardNo, userToken, originalDateStr, originalTimeStr, userToken, notificationResponse))
-			}
-		} else {
-			logger.AppLogger.Sugar().Infof("Spending Alert not triggered for user token '%s' (Flag: %t, LastLogin within 90 days: %t)", userToken, alertSettingResponse.SpendingAlertFlag, isLastLoginWithin90Days(alertSettingResponse.LastLogin))
-			results = append(results, fmt.Sprintf("%s,%s,%s,%s,%s,Not Triggered", cardNo, userToken, originalDateStr, originalTimeStr, userToken))
+		row, rec, interrupted := p.processLine(ctx, scanner.Text())
+		if interrupted {
+			return nil, ctx.Err()
+		}
+		if row != "" {
+			results = append(results, row)
+		}
+		if err := cp.Record(lineNo, row); err != nil {
+			return nil, err
 		}
+		rec.File, rec.Line = p.sourceFile, lineNo
+		recorder.Record(rec)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -90,16 +251,160 @@ func ProcessSpendingAlertFile(cfg *config.Config, filePath string) ([]string, er
 	return results, nil
 }
 
-func isLastLoginWithin90Days(lastLogin string) bool {
-	if lastLogin == "" {
-		return false
+// processLine processes a single record and returns its result row, or "" for a line too short
+// to identify the customer, and its outcome for the run history. It reports interrupted, with no row, when ctx was
+// cancelled before the notification was queued, so the record can be processed again.
+func (p *fileProcessor) processLine(ctx context.Context, line string) (string, runs.Record, bool) {
+	cfg := p.cfg
+	if len(line) < userTokenStart+userTokenLength {
+		logger.FromContext(ctx).Warnw("Skipping line due to insufficient length", "line_length", len(line))
+		return "", runs.Record{Outcome: runs.OutcomeSkipped, Detail: "insufficient length"}, false
+	}
+
+	record := parseSpendingAlertRecord(line, cfg.Masking.CardNoFormat, cfg.SpendingAlert.RecordFields)
+	userToken := record.UserToken
+	correlationID := util.GenerateRequestID()
+	recordCtx := correlation.WithCorrelationID(ctx, correlationID)
+	log := logger.FromContext(recordCtx)
+
+	alertSettingResponse, err := getAlertSetting(recordCtx, p.alertSettingClient, userToken, cfg.APIEndpoints.AlertSettingMaxAttempts)
+	if err != nil && ctx.Err() != nil {
+		log.Warnw("Get Alert Setting API call interrupted", "user_token", userToken, "error", err)
+		return "", runs.Record{}, true
+	}
+	if err != nil {
+		log.Errorw("Failed to call Get Alert Setting API", "user_token", userToken, "retryable", api.IsRetryable(err), "error", err)
+		return p.resultRow(record, "Alert Setting Failed "+failureCode(err), ruleAlertSetting, correlationID),
+			runs.Record{Outcome: runs.OutcomeFailed, Detail: ruleAlertSetting + " " + failureCode(err), CorrelationID: correlationID}, false
+	}
+
+	now := util.Now()
+	eligibility := evaluateEligibility(p.rules, eligibilityInput{record: record, setting: alertSettingResponse, now: now})
+	if !eligibility.Eligible {
+		log.Infow("Spending Alert not triggered", "user_token", userToken, "rule", eligibility.Rule)
+		return p.resultRow(record, "Not Triggered", eligibility.Rule, correlationID),
+			runs.Record{Outcome: runs.OutcomeNotTriggered, Detail: eligibility.Rule, CorrelationID: correlationID}, false
+	}
+
+	lang := resolveLanguage(alertSettingResponse.PreferredLanguage, cfg.SpendingAlert.DefaultLanguage)
+	notificationRequest, err := buildNotificationRequest(p.templates, record, topic.Resolve(cfg.SpendingAlert.Topic, record.fields()), lang, alertSettingResponse.PreferredChannel)
+	if err != nil {
+		log.Errorw("Failed to build notification request", "user_token", userToken, "error", err)
+		return p.resultRow(record, "Message Build Failed", ruleMessageTemplate, correlationID),
+			runs.Record{Outcome: runs.OutcomeFailed, Detail: ruleMessageTemplate + " " + err.Error(), CorrelationID: correlationID}, false
+	}
+
+	deliverAt := nextAllowedTime(now, quietHoursFor(p.globalQuietHours, alertSettingResponse))
+	notification := &queue.Notification{
+		Batch:         batchName,
+		RunID:         correlation.RunID(ctx),
+		CorrelationID: correlationID,
+		SourceFile:    p.sourceFile,
+		ResultColumns: []string{record.CardNo, userToken, record.Date, record.Time, userToken},
+		Request:       notificationRequest,
+		NotBefore:     deliverAt,
+	}
+	if ctx.Err() != nil {
+		return "", runs.Record{}, true
+	}
+	if err := p.outbox.Enqueue(notification); err != nil {
+		log.Errorw("Failed to enqueue notification", "user_token", userToken, "error", err)
+		return p.resultRow(record, "Enqueue Failed", ruleOutbox, correlationID),
+			runs.Record{Outcome: runs.OutcomeFailed, Detail: ruleOutbox + " " + err.Error(), CorrelationID: correlationID}, false
+	}
+
+	if deliverAt.After(now) {
+		log.Infow("Notification deferred by quiet hours", "id", notification.ID, "user_token", userToken, "not_before", deliverAt)
+		return p.resultRow(record, "Deferred until "+deliverAt.Format(time.RFC3339), ruleQuietHours, correlationID),
+			runs.Record{Outcome: runs.OutcomeDeferred, Detail: "until " + deliverAt.Format(time.RFC3339), CorrelationID: correlationID, NotificationID: notification.ID}, false
+	}
+	log.Infow("Notification queued", "id", notification.ID, "user_token", userToken)
+	return p.resultRow(record, "Queued "+notification.ID, eligibility.Rule, correlationID),
+		runs.Record{Outcome: runs.OutcomeQueued, Detail: eligibility.Rule, CorrelationID: correlationID, NotificationID: notification.ID}, false
+}
+
+// resultRow formats the send result line for record; the deciding rule and correlation ID
+// are appended only when result_extra_columns is set, keeping the original file format otherwise.
+func (p *fileProcessor) resultRow(record spendingAlertRecord, status, rule, correlationID string) string {
+	row := fmt.Sprintf("%s,%s,%s,%s,%s,%s", record.CardNo, record.UserToken, record.Date, record.Time, record.UserToken, status)
+	if p.cfg.SpendingAlert.ResultExtras {
+		row += "," + rule + "," + correlationID
 	}
-	layout := "2006-01-02 15:04:05"
-	parsedTime, err := time.Parse(layout, lastLogin)
+	return row
+}
+
+// getAlertSetting calls the Get Alert Setting API, retrying retryable failures up to maxAttempts times.
+func getAlertSetting(ctx context.Context, client *api.AlertSettingClient, userToken string, maxAttempts int) (*model.AlertSettingResponse, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := client.GetAlertSetting(ctx, userToken)
+		if err == nil || !api.IsRetryable(err) || attempt >= maxAttempts {
+			return response, err
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// failureCode returns the business response code or HTTP status of a failed API call, if any.
+func failureCode(err error) string {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) {
+		return ""
+	}
+	if respErr.ResponseCode != "" {
+		return respErr.ResponseCode
+	}
+	return fmt.Sprintf("HTTP%d", respErr.StatusCode)
+}
+
+// buildNotificationRequest renders the message in lang only, populating the push and/or inbox
+// fields according to the customer's preferred channel (both when no preference is given).
+func buildNotificationRequest(templates *msgtemplate.Store, record spendingAlertRecord, topicCode, lang, channel string) (model.NotificationRequest, error) {
+	msg, err := templates.Render(templateTopic, lang, record)
 	if err != nil {
-		logger.AppLogger.Sugar().Warnf("Failed to parse last login time '%s': %v", lastLogin, err)
-		return false
+		return model.NotificationRequest{}, err
 	}
-	ninetyDaysAgo := time.Now().AddDate(0, 0, -90)
-	return parsedTime.After(ninetyDaysAgo)
+
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	push := channel != channelInbox
+	inbox := channel != channelPush
+
+	request := model.NotificationRequest{
+		Usertoken: record.UserToken,
+		Topiccode: topicCode,
+	}
+	switch lang {
+	case languageTH:
+		if push {
+			request.TitleTH, request.MessageTH = msg.Title, msg.Message
+		}
+		if inbox {
+			request.TitleinboxTH, request.MessageinboxTH = msg.TitleInbox, msg.MessageInbox
+		}
+	case languageEN:
+		if push {
+			request.TitleEN, request.MessageEN = msg.Title, msg.Message
+		}
+		if inbox {
+			request.TitleinboxEN, request.MessageinboxEN = msg.TitleInbox, msg.MessageInbox
+		}
+	}
+	return request, nil
+}
+
+// resolveLanguage returns the customer's preferred language if supported, otherwise the fallback.
+func resolveLanguage(preferred, fallback string) string {
+	lang := strings.ToLower(strings.TrimSpace(preferred))
+	if isSupportedLanguage(lang) {
+		return lang
+	}
+	return strings.ToLower(fallback)
+}
+
+func isSupportedLanguage(lang string) bool {
+	lang = strings.ToLower(lang)
+	return lang == languageTH || lang == languageEN
 }
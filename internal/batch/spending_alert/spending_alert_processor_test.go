@@ -0,0 +1,336 @@
+package spending_alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+	"notification_batch/internal/msgtemplate"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/topic"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// testLine builds a send file line with the fixed columns filled and extra appended at column 80.
+func testLine(extra string) string {
+	line := []byte(strings.Repeat(" ", 80))
+	copy(line[cardNoStart:], "4111111111111111")
+	copy(line[userTokenStart:], "token-0001")
+	copy(line[originalDateStart:], "2026-10-19")
+	copy(line[originalTimeStart:], "08:15:00")
+	return string(line) + extra
+}
+
+func TestParseSpendingAlertRecord(t *testing.T) {
+	layout := map[string]config.FieldPosition{
+		fieldAmount:  {Start: 80, Length: 10},
+		fieldChannel: {Start: 90, Length: 6},
+	}
+	tests := []struct {
+		name   string
+		line   string
+		layout map[string]config.FieldPosition
+		want   spendingAlertRecord
+	}{
+		{
+			name: "fixed fields only",
+			line: testLine("0000001500ONLINE"),
+			want: spendingAlertRecord{CardNo: "************1111", UserToken: "token-0001", Date: "2026-10-19", Time: "08:15:00"},
+		},
+		{
+			name:   "configured fields",
+			line:   testLine("   1500.50ONLINE"),
+			layout: layout,
+			want:   spendingAlertRecord{CardNo: "************1111", UserToken: "token-0001", Date: "2026-10-19", Time: "08:15:00", Amount: "1500.50", Channel: "ONLINE"},
+		},
+		{
+			name:   "short line",
+			line:   testLine("   99"),
+			layout: layout,
+			want:   spendingAlertRecord{CardNo: "************1111", UserToken: "token-0001", Date: "2026-10-19", Time: "08:15:00", Amount: "99"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSpendingAlertRecord(tt.line, util.CardMaskLast4, tt.layout); got != tt.want {
+				t.Errorf("parseSpendingAlertRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	loginWithin90 := config.EligibilityConfig{LastLoginWithinDays: 90}
+	tests := []struct {
+		name    string
+		batch   config.BatchConfig
+		wantErr bool
+	}{
+		{
+			name:  "no configured fields",
+			batch: config.BatchConfig{Eligibility: loginWithin90},
+		},
+		{
+			name:    "unknown field",
+			batch:   config.BatchConfig{Eligibility: loginWithin90, RecordFields: map[string]config.FieldPosition{"branch": {Start: 80, Length: 4}}},
+			wantErr: true,
+		},
+		{
+			name:    "zero length",
+			batch:   config.BatchConfig{Eligibility: loginWithin90, RecordFields: map[string]config.FieldPosition{fieldAmount: {Start: 80}}},
+			wantErr: true,
+		},
+		{
+			name:    "topic rule on unmapped field",
+			batch:   config.BatchConfig{Eligibility: loginWithin90, Topic: config.TopicConfig{Default: "SPENDING_ALERT", Rules: []config.TopicRule{{Field: fieldChannel, Equals: "POS", TopicCode: "POS"}}}},
+			wantErr: true,
+		},
+		{
+			name: "topic rule on mapped field",
+			batch: config.BatchConfig{
+				Eligibility:  loginWithin90,
+				RecordFields: map[string]config.FieldPosition{fieldChannel: {Start: 90, Length: 6}},
+				Topic:        config.TopicConfig{Default: "SPENDING_ALERT", Rules: []config.TopicRule{{Field: fieldChannel, Equals: "POS", TopicCode: "POS"}}},
+			},
+		},
+		{
+			name:    "customer threshold without amount",
+			batch:   config.BatchConfig{Eligibility: config.EligibilityConfig{LastLoginWithinDays: 90, UseCustomerThreshold: true}},
+			wantErr: true,
+		},
+		{
+			name:    "excluded categories without merchant category",
+			batch:   config.BatchConfig{Eligibility: config.EligibilityConfig{LastLoginWithinDays: 90, ExcludedMerchantCategories: []string{"7995"}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing last login window",
+			batch:   config.BatchConfig{Eligibility: config.EligibilityConfig{LastLoginWithinDays: 0}},
+			wantErr: true,
+		},
+		{
+			name:    "negative last login window",
+			batch:   config.BatchConfig{Eligibility: config.EligibilityConfig{LastLoginWithinDays: -1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{SpendingAlert: tt.batch}
+			if cfg.SpendingAlert.Topic.Default == "" {
+				cfg.SpendingAlert.Topic.Default = "SPENDING_ALERT"
+			}
+			cfg.SpendingAlert.DefaultLanguage = languageTH
+			if err := ValidateConfig(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResultRow(t *testing.T) {
+	record := spendingAlertRecord{CardNo: "************1111", UserToken: "token-0001", Date: "2026-10-19", Time: "08:15:00"}
+	tests := []struct {
+		name   string
+		extras bool
+		want   string
+	}{
+		{"original format", false, "************1111,token-0001,2026-10-19,08:15:00,token-0001,Not Triggered"},
+		{"extra columns", true, "************1111,token-0001,2026-10-19,08:15:00,token-0001,Not Triggered,min_amount,RQ1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fileProcessor{cfg: &config.Config{SpendingAlert: config.BatchConfig{ResultExtras: tt.extras}}}
+			if got := p.resultRow(record, "Not Triggered", ruleMinAmount, "RQ1"); got != tt.want {
+				t.Errorf("resultRow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessSpendingAlertFileWritesBuildFailureRow(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sa_20261019.txt")
+	if err := os.WriteFile(file, []byte(testLine("")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Only English is defined, so rendering the Thai default language fails.
+	templates := filepath.Join(dir, "templates.yaml")
+	if err := os.WriteFile(templates, []byte("spending_alert:\n  en:\n    title: \"Spending Alert\"\n    message: \"Card {{.CardNo}}\"\n    title_inbox: \"Spending Alert\"\n    message_inbox: \"Card {{.CardNo}}\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := alertSettingServer(t, "", func() {}, false)
+	cfg := processorTestConfig(t, server.URL)
+	cfg.TemplateFile = templates
+
+	results, err := ProcessSpendingAlertFile(context.Background(), cfg, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "************1111,token-0001,2026-10-19,08:15:00,token-0001,Message Build Failed"
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("results = %q, want [%q]", results, want)
+	}
+}
+
+func TestRecordTopic(t *testing.T) {
+	topicCfg := config.TopicConfig{
+		Default: "SPENDING_ALERT",
+		Rules:   []config.TopicRule{{Field: fieldChannel, Equals: "ONLINE", TopicCode: "SPENDING_ONLINE"}},
+	}
+	tests := []struct {
+		name   string
+		layout map[string]config.FieldPosition
+		line   string
+		want   string
+	}{
+		{"rule matches the mapped field", map[string]config.FieldPosition{fieldChannel: {Start: 80, Length: 6}}, testLine("ONLINE"), "SPENDING_ONLINE"},
+		{"rule does not match", map[string]config.FieldPosition{fieldChannel: {Start: 80, Length: 6}}, testLine("POS"), "SPENDING_ALERT"},
+		{"field not mapped", nil, testLine("ONLINE"), "SPENDING_ALERT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := parseSpendingAlertRecord(tt.line, util.CardMaskLast4, tt.layout)
+			if got := topic.Resolve(topicCfg, record.fields()); got != tt.want {
+				t.Errorf("topic = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	tests := []struct {
+		preferred string
+		fallback  string
+		want      string
+	}{
+		{"en", languageTH, languageEN},
+		{" TH ", languageEN, languageTH},
+		{"", languageTH, languageTH},
+		{"", "EN", languageEN},
+		{"jp", languageEN, languageEN},
+	}
+	for _, tt := range tests {
+		if got := resolveLanguage(tt.preferred, tt.fallback); got != tt.want {
+			t.Errorf("resolveLanguage(%q, %q) = %q, want %q", tt.preferred, tt.fallback, got, tt.want)
+		}
+	}
+}
+
+func TestBuildNotificationRequest(t *testing.T) {
+	templates, err := msgtemplate.Get("../../../config/templates.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := spendingAlertRecord{CardNo: "************1111", UserToken: "token-0001", Date: "2026-10-19", Time: "08:15:00"}
+
+	// set lists the message fields expected to be filled, in the order
+	// TitleTH, MessageTH, TitleinboxTH, MessageinboxTH, TitleEN, MessageEN, TitleinboxEN, MessageinboxEN.
+	tests := []struct {
+		name    string
+		lang    string
+		channel string
+		set     [8]bool
+	}{
+		{"thai push and inbox", languageTH, "", [8]bool{true, true, true, true}},
+		{"thai push only", languageTH, "PUSH", [8]bool{true, true}},
+		{"thai inbox only", languageTH, "inbox", [8]bool{false, false, true, true}},
+		{"english push and inbox", languageEN, "unknown", [8]bool{4: true, 5: true, 6: true, 7: true}},
+		{"english push only", languageEN, "push", [8]bool{4: true, 5: true}},
+		{"english inbox only", languageEN, " Inbox ", [8]bool{6: true, 7: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := buildNotificationRequest(templates, record, "SPENDING_ALERT", tt.lang, tt.channel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Usertoken != record.UserToken || req.Topiccode != "SPENDING_ALERT" {
+				t.Errorf("request = %+v, want the record's token and topic", req)
+			}
+			got := [8]string{req.TitleTH, req.MessageTH, req.TitleinboxTH, req.MessageinboxTH, req.TitleEN, req.MessageEN, req.TitleinboxEN, req.MessageinboxEN}
+			for i := range got {
+				if (got[i] != "") != tt.set[i] {
+					t.Errorf("field %d = %q, want set %v", i, got[i], tt.set[i])
+				}
+			}
+			if tt.lang == languageEN && tt.set[5] && !strings.Contains(req.MessageEN, record.CardNo) {
+				t.Errorf("MessageEN = %q, want the masked card number", req.MessageEN)
+			}
+		})
+	}
+
+	if _, err := buildNotificationRequest(templates, record, "SPENDING_ALERT", "jp", ""); err == nil {
+		t.Error("buildNotificationRequest() with a language without templates error = nil")
+	}
+}
+
+func TestProcessSpendingAlertFilePropagatesCorrelationID(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	file := filepath.Join(t.TempDir(), "sa_20261019.txt")
+	if err := os.WriteFile(file, []byte(testLine("")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var header http.Header
+	var body model.AlertSettingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(model.AlertSettingResponse{
+			ResponseCode:      "0000",
+			UserToken:         body.UserToken,
+			SpendingAlertFlag: true,
+			LastLogin:         util.Now().Format(defaultLastLoginLayout),
+		})
+	}))
+	t.Cleanup(server.Close)
+	cfg := processorTestConfig(t, server.URL)
+	cfg.SpendingAlert.ResultExtras = true
+
+	runID := "spending_alert-run-1"
+	results, err := ProcessSpendingAlertFile(correlation.WithRunID(context.Background(), runID), cfg, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	correlationID := header.Get(correlation.HeaderCorrelationID)
+	if correlationID == "" {
+		t.Fatalf("%s header not sent", correlation.HeaderCorrelationID)
+	}
+	if got := header.Get(correlation.HeaderRunID); got != runID {
+		t.Errorf("%s header = %q, want %q", correlation.HeaderRunID, got, runID)
+	}
+	if body.CorrelationID != correlationID {
+		t.Errorf("request CorrelationID = %q, want the header %q", body.CorrelationID, correlationID)
+	}
+	if len(results) != 1 || !strings.HasSuffix(results[0], ","+correlationID) {
+		t.Errorf("results = %q, want one row ending with %q", results, correlationID)
+	}
+
+	outbox, err := queue.Open(queue.OutboxPath(cfg.DataPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := outbox.List(queue.StatePending, queue.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("queued %d notifications, want 1", len(pending))
+	}
+	if pending[0].RunID != runID || pending[0].CorrelationID != correlationID {
+		t.Errorf("queued run and correlation IDs = %q, %q, want %q, %q", pending[0].RunID, pending[0].CorrelationID, runID, correlationID)
+	}
+}
@@ -0,0 +1,163 @@
+package calendar
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"notification_batch/internal/logger"
+	"notification_batch/internal/util"
+
+	"gopkg.in/yaml.v2"
+)
+
+const dateLayout = "2006-01-02"
+
+// Holiday policies applied to scheduled runs that fall on a holiday.
+const (
+	PolicySkip  = "skip"
+	PolicyShift = "shift"
+)
+
+var (
+	calendars   = make(map[string]*Calendar)
+	calendarsMu sync.Mutex
+)
+
+// holidayDef is the YAML definition of a single holiday.
+type holidayDef struct {
+	Date string `yaml:"date"`
+	Name string `yaml:"name"`
+}
+
+// Calendar is a bank holiday calendar, reloaded when its file changes.
+type Calendar struct {
+	path     string
+	mu       sync.RWMutex
+	modTime  time.Time
+	holidays map[string]string
+	years    map[int]bool
+}
+
+// Get returns the calendar for the given file, loading it on first use.
+func Get(path string) (*Calendar, error) {
+	calendarsMu.Lock()
+	defer calendarsMu.Unlock()
+
+	if c, ok := calendars[path]; ok {
+		return c, nil
+	}
+	c := &Calendar{path: path}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	calendars[path] = c
+	return c, nil
+}
+
+// ValidPolicy reports whether policy is a supported holiday policy; empty means skip.
+func ValidPolicy(policy string) bool {
+	return policy == "" || policy == PolicySkip || policy == PolicyShift
+}
+
+// Holiday reports whether t falls on a holiday and returns its name.
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.reloadIfModified()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.holidays[t.Format(dateLayout)]
+	return name, ok
+}
+
+// Covers reports whether the calendar lists any holiday in year; a year without entries
+// means the calendar has not been updated for it.
+func (c *Calendar) Covers(year int) bool {
+	if c == nil {
+		return true
+	}
+	c.reloadIfModified()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.years[year]
+}
+
+// IsBusinessDay reports whether t is not a holiday and, when weekdaysOnly is set, not a weekend.
+func (c *Calendar) IsBusinessDay(t time.Time, weekdaysOnly bool) bool {
+	if weekdaysOnly && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// NextBusinessDay returns the same time of day on the first business day after t.
+func (c *Calendar) NextBusinessDay(t time.Time, weekdaysOnly bool) time.Time {
+	next := t.AddDate(0, 0, 1)
+	for i := 0; i < 366 && !c.IsBusinessDay(next, weekdaysOnly); i++ {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (c *Calendar) load() error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat holiday calendar '%s': %v", c.path, err)
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read holiday calendar '%s': %v", c.path, err)
+	}
+
+	var defs struct {
+		Holidays []holidayDef `yaml:"holidays"`
+	}
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return fmt.Errorf("failed to unmarshal holiday calendar '%s': %v", c.path, err)
+	}
+	holidays := make(map[string]string, len(defs.Holidays))
+	years := make(map[int]bool)
+	for _, def := range defs.Holidays {
+		date, err := time.Parse(dateLayout, def.Date)
+		if err != nil {
+			return fmt.Errorf("invalid holiday date '%s' in '%s', expected YYYY-MM-DD", def.Date, c.path)
+		}
+		holidays[def.Date] = def.Name
+		years[date.Year()] = true
+	}
+
+	c.mu.Lock()
+	c.holidays = holidays
+	c.years = years
+	c.modTime = info.ModTime()
+	c.mu.Unlock()
+
+	if year := util.Now().Year(); !years[year] {
+		logger.AppLogger.Sugar().Errorw("Holiday calendar has no holidays for the current year", "alert", "holiday_calendar_outdated", "calendar", c.path, "year", year)
+	}
+	return nil
+}
+
+func (c *Calendar) reloadIfModified() {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return
+	}
+	c.mu.RLock()
+	modified := info.ModTime().After(c.modTime)
+	c.mu.RUnlock()
+	if !modified {
+		return
+	}
+	if err := c.load(); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to reload holiday calendar, keeping previous version: %v", err)
+		return
+	}
+	logger.AppLogger.Sugar().Infof("Reloaded holiday calendar '%s'", c.path)
+}
@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification_batch/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+func writeCalendar(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "holidays.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	return t
+}
+
+func TestCalendar(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	c, err := Get(writeCalendar(t, `holidays:
+  - date: "2026-10-23"
+    name: "Chulalongkorn Day"
+  - date: "2026-10-26"
+    name: "Special Holiday"
+  - date: "2026-12-31"
+    name: "New Year's Eve"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, ok := c.Holiday(date("2026-10-23 08:00")); !ok || name != "Chulalongkorn Day" {
+		t.Errorf("Holiday() = %q, %v", name, ok)
+	}
+	if _, ok := c.Holiday(date("2026-10-22 08:00")); ok {
+		t.Error("Holiday() = true on a business day")
+	}
+
+	tests := []struct {
+		name         string
+		from         string
+		weekdaysOnly bool
+		want         string
+	}{
+		{"next day", "2026-10-20 08:00", true, "2026-10-21 08:00"},
+		{"over a weekend and a holiday", "2026-10-23 08:00", true, "2026-10-27 08:00"},
+		{"weekends count without weekdays only", "2026-10-23 08:00", false, "2026-10-24 08:00"},
+		{"into the next year", "2026-12-30 17:30", true, "2027-01-01 17:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.NextBusinessDay(date(tt.from), tt.weekdaysOnly); !got.Equal(date(tt.want)) {
+				t.Errorf("NextBusinessDay(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+
+	if !c.Covers(2026) || c.Covers(2027) {
+		t.Errorf("Covers(2026) = %v, Covers(2027) = %v", c.Covers(2026), c.Covers(2027))
+	}
+}
+
+func TestNilCalendar(t *testing.T) {
+	var c *Calendar
+	if _, ok := c.Holiday(date("2026-10-23 08:00")); ok {
+		t.Error("Holiday() = true without a calendar")
+	}
+	if !c.Covers(2026) || !c.IsBusinessDay(date("2026-10-23 08:00"), true) {
+		t.Error("a missing calendar should treat every weekday as a business day")
+	}
+}
+
+func TestGetInvalidDate(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	if _, err := Get(writeCalendar(t, "holidays:\n  - date: \"23/10/2026\"\n    name: \"Chulalongkorn Day\"\n")); err == nil {
+		t.Error("Get() error = nil, want an invalid date error")
+	}
+}
+
+func TestBundledCalendar(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	c, err := Get("../../config/holidays.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []string{"2026-03-03", "2026-04-06", "2026-05-04", "2026-06-01", "2026-06-03", "2026-07-29"} {
+		if _, ok := c.Holiday(date(day + " 00:00")); !ok {
+			t.Errorf("config/holidays.yaml is missing %s", day)
+		}
+	}
+}
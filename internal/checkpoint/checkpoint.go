@@ -0,0 +1,120 @@
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"notification_batch/internal/util"
+)
+
+// Checkpoint records how far a file has been processed and the result rows produced so far,
+// so that an interrupted run can resume after the last completed record.
+type Checkpoint struct {
+	linePath    string
+	resultsPath string
+}
+
+// Open returns the checkpoint for the file at path in dir, creating dir if needed.
+// Checkpoints are keyed by file name and content, so a different file delivered under
+// the same name starts from the beginning instead of resuming another file's progress.
+func Open(dir, path string) (*Checkpoint, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory '%s': %v", dir, err)
+	}
+	sum, err := contentHash(path)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Join(dir, filepath.Base(path)+"."+sum)
+	return &Checkpoint{
+		linePath:    base + ".line",
+		resultsPath: base + ".results",
+	}, nil
+}
+
+// Load returns the last completed line number and its result rows; both are zero if there is no checkpoint.
+func (c *Checkpoint) Load() (int, []string, error) {
+	data, err := os.ReadFile(c.linePath)
+	if os.IsNotExist(err) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read checkpoint '%s': %v", c.linePath, err)
+	}
+	line, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid checkpoint '%s': %v", c.linePath, err)
+	}
+
+	file, err := os.Open(c.resultsPath)
+	if os.IsNotExist(err) {
+		return line, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open checkpoint results '%s': %v", c.resultsPath, err)
+	}
+	defer file.Close()
+
+	var results []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		results = append(results, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to read checkpoint results '%s': %v", c.resultsPath, err)
+	}
+	return line, results, nil
+}
+
+// Record marks line as completed, appending its result row if there is one.
+// The row is written before the line number so a crash in between at worst repeats the row.
+func (c *Checkpoint) Record(line int, row string) error {
+	if row != "" {
+		file, err := os.OpenFile(c.resultsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open checkpoint results '%s': %v", c.resultsPath, err)
+		}
+		_, err = file.WriteString(row + "\n")
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write checkpoint results '%s': %v", c.resultsPath, err)
+		}
+	}
+
+	if err := util.WriteFileAtomic(c.linePath, []byte(strconv.Itoa(line)), 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint '%s': %v", c.linePath, err)
+	}
+	return nil
+}
+
+// Clear removes the checkpoint once the file has been fully processed and its results delivered.
+func (c *Checkpoint) Clear() error {
+	for _, path := range []string{c.linePath, c.resultsPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove checkpoint '%s': %v", path, err)
+		}
+	}
+	return nil
+}
+
+// contentHash returns a short hex digest of the file at path.
+func contentHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open '%s' for checkpointing: %v", path, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to read '%s' for checkpointing: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
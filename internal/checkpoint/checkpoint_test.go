@@ -0,0 +1,111 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir, src := t.TempDir(), filepath.Join(t.TempDir(), "sa_20261019.txt")
+	writeFile(t, src, "line 1\nline 2\nline 3\n")
+
+	cp, err := Open(dir, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line, results, err := cp.Load(); err != nil || line != 0 || results != nil {
+		t.Fatalf("Load() on a new checkpoint = %d, %v, %v", line, results, err)
+	}
+
+	steps := []struct {
+		line int
+		row  string
+	}{{1, "row 1"}, {2, ""}, {3, "row 3"}}
+	for i, step := range steps {
+		if err := cp.Record(step.line, step.row); err != nil {
+			t.Fatal(err)
+		}
+		// A run interrupted after this record resumes from a freshly opened checkpoint.
+		reopened, err := Open(dir, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line, results, err := reopened.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, s := range steps[:i+1] {
+			if s.row != "" {
+				want = append(want, s.row)
+			}
+		}
+		if line != step.line || !reflect.DeepEqual(results, want) {
+			t.Errorf("after line %d Load() = %d, %v, want %d, %v", step.line, line, results, step.line, want)
+		}
+	}
+
+	if err := cp.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if line, results, err := cp.Load(); err != nil || line != 0 || results != nil {
+		t.Errorf("Load() after Clear() = %d, %v, %v", line, results, err)
+	}
+}
+
+func TestKeyedByContent(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      string
+		second     string
+		secondName string
+		wantResume bool
+	}{
+		{"same file downloaded again", "a\nb\n", "a\nb\n", "sa.txt", true},
+		{"new file under the same name", "a\nb\n", "c\nd\n", "sa.txt", false},
+		{"same content under another name", "a\nb\n", "a\nb\n", "sa_copy.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpDir := t.TempDir()
+			first := filepath.Join(t.TempDir(), "sa.txt")
+			writeFile(t, first, tt.first)
+			cp, err := Open(cpDir, first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cp.Record(1, "row 1"); err != nil {
+				t.Fatal(err)
+			}
+
+			second := filepath.Join(t.TempDir(), tt.secondName)
+			writeFile(t, second, tt.second)
+			cp, err = Open(cpDir, second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line, _, err := cp.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed := line == 1; resumed != tt.wantResume {
+				t.Errorf("resumed = %t, want %t", resumed, tt.wantResume)
+			}
+		})
+	}
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	if _, err := Open(t.TempDir(), filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Open() of a missing file error = nil, want an error")
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"notification_batch/internal/util"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -27,32 +31,162 @@ type FTPConfig struct {
 
 // APIEndpoints defines the endpoints for external APIs.
 type APIEndpoints struct {
-	GetAlertSetting  string        `yaml:"get_alert_setting"`
-	SendNotification string        `yaml:"send_notification"`
-	Timeout          time.Duration `yaml:"timeout"`
+	GetAlertSetting         string              `yaml:"get_alert_setting"`
+	SendNotification        string              `yaml:"send_notification"`
+	Timeout                 time.Duration       `yaml:"timeout"`
+	AlertSettingMaxAttempts int                 `yaml:"alert_setting_max_attempts"`
+	ResponseCodes           ResponseCodesConfig `yaml:"response_codes"`
 }
 
 // ScheduleConfig defines the schedule for batch jobs.
+// A job runs on its cron expression if set, otherwise daily at each of its times.
 type ScheduleConfig struct {
-	SendTime   string `yaml:"send_time"`
-	ResultTime string `yaml:"result_time"`
+	SendTime        string        `yaml:"send_time"`
+	ResultTime      string        `yaml:"result_time"`
+	SendTimes       []string      `yaml:"send_times"`
+	ResultTimes     []string      `yaml:"result_times"`
+	SendCron        string        `yaml:"send_cron"`
+	ResultCron      string        `yaml:"result_cron"`
+	WeekdaysOnly    bool          `yaml:"weekdays_only"`
+	HolidayCalendar string        `yaml:"holiday_calendar"`
+	HolidayPolicy   string        `yaml:"holiday_policy"`
+	OverlapPolicy   string        `yaml:"overlap_policy"`
+	SendMisfire     string        `yaml:"send_misfire_policy"`
+	ResultMisfire   string        `yaml:"result_misfire_policy"`
+	ResultWaitsSend bool          `yaml:"result_waits_for_send"`
+	ResultWait      time.Duration `yaml:"result_wait_timeout"`
+}
+
+// TopicRule maps records whose field equals a value to a specific topic code.
+type TopicRule struct {
+	Field     string `yaml:"field"`
+	Equals    string `yaml:"equals"`
+	TopicCode string `yaml:"topic_code"`
+}
+
+// TopicConfig defines the topic code used for a batch and optional per-record overrides.
+type TopicConfig struct {
+	Default string      `yaml:"default"`
+	Rules   []TopicRule `yaml:"rules"`
+}
+
+// QuietHoursConfig defines a daily window (HH:MM, may span midnight) in which notifications are not sent.
+type QuietHoursConfig struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// EligibilityConfig defines the rules a record must satisfy before a notification is sent.
+// LastLoginWithinDays is required; zero values disable the other rules.
+type EligibilityConfig struct {
+	LastLoginWithinDays        int      `yaml:"last_login_within_days"`
+	LastLoginLayout            string   `yaml:"last_login_layout"`
+	MinAmount                  float64  `yaml:"min_amount"`
+	ExcludedMerchantCategories []string `yaml:"excluded_merchant_categories"`
+	UseCustomerThreshold       bool     `yaml:"use_customer_threshold"`
 }
 
 // BatchConfig defines the configuration for a specific batch (Spending Alert or e-NCB).
 type BatchConfig struct {
-	FTP          FTPConfig      `yaml:"ftp"`
-	Schedule     ScheduleConfig `yaml:"schedule"`
-	ResultPrefix string         `yaml:"result_file_prefix"`
+	FTP             FTPConfig                `yaml:"ftp"`
+	Schedule        ScheduleConfig           `yaml:"schedule"`
+	Topic           TopicConfig              `yaml:"topic"`
+	DefaultLanguage string                   `yaml:"default_language"`
+	Eligibility     EligibilityConfig        `yaml:"eligibility"`
+	QuietHours      QuietHoursConfig         `yaml:"quiet_hours"`
+	ResultPrefix    string                   `yaml:"result_file_prefix"`
+	ResultExtras    bool                     `yaml:"result_extra_columns"`
+	Watch           WatchConfig              `yaml:"watch"`
+	RecordFields    map[string]FieldPosition `yaml:"record_fields"`
+}
+
+// FieldPosition locates a fixed-width field in a batch file line (0-based start and length).
+type FieldPosition struct {
+	Start  int `yaml:"start"`
+	Length int `yaml:"length"`
+}
+
+// WatchConfig defines polling of the send folder for new files, an alternative to the send schedule.
+type WatchConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	StablePolls  int           `yaml:"stable_polls"`
+	Pattern      string        `yaml:"pattern"`
+}
+
+// MaskingConfig defines how sensitive values are masked in messages, results and logs.
+type MaskingConfig struct {
+	CardNoFormat string `yaml:"card_no_format"`
+}
+
+// RedactionRule defines how a single field is redacted in logs (mask, hash or drop).
+type RedactionRule struct {
+	Field  string `yaml:"field"`
+	Action string `yaml:"action"`
+}
+
+// RedactionConfig defines the field redaction rules applied to API and application logs.
+type RedactionConfig struct {
+	HashSalt string          `yaml:"hash_salt"`
+	Rules    []RedactionRule `yaml:"rules"`
+}
+
+// DispatcherConfig defines how the outbound notification queue is drained.
+type DispatcherConfig struct {
+	Workers           int           `yaml:"workers"`
+	PollInterval      time.Duration `yaml:"poll_interval"`
+	MaxAttempts       int           `yaml:"max_attempts"`
+	RetryBackoff      time.Duration `yaml:"retry_backoff"`
+	SentRetentionDays int           `yaml:"sent_retention_days"`
+}
+
+// ResponseCodeMapping classifies API business response codes as success, retryable or permanent failure.
+type ResponseCodeMapping struct {
+	Success   []string `yaml:"success"`
+	Retryable []string `yaml:"retryable"`
+	Permanent []string `yaml:"permanent"`
+	Default   string   `yaml:"default"`
+}
+
+// ResponseCodesConfig defines the response code mappings for each external API.
+type ResponseCodesConfig struct {
+	Notification ResponseCodeMapping `yaml:"notification"`
+	AlertSetting ResponseCodeMapping `yaml:"alert_setting"`
+}
+
+// OpsAPIConfig defines access to the operational HTTP endpoints.
+// APIKeys are normally set through the comma-separated OPS_API_KEYS environment variable.
+type OpsAPIConfig struct {
+	APIKeys []string `yaml:"api_keys"`
+}
+
+// LeaderElectionConfig defines how replicas elect the one that runs scheduled jobs.
+type LeaderElectionConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Backend       string        `yaml:"backend"`
+	LockPath      string        `yaml:"lock_path"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
 }
 
 // Config holds the entire application configuration.
 type Config struct {
-	Environment   string       `yaml:"environment"`
-	APIEndpoints  APIEndpoints `yaml:"api_endpoints"`
-	SpendingAlert BatchConfig  `yaml:"spending_alert"`
-	ENCB          BatchConfig  `yaml:"e_ncb"`
-	LogPath       string       `yaml:"log_path"`
-	APILogPrefix  string       `yaml:"api_log_prefix"`
+	Environment         string               `yaml:"environment"`
+	APIEndpoints        APIEndpoints         `yaml:"api_endpoints"`
+	SpendingAlert       BatchConfig          `yaml:"spending_alert"`
+	ENCB                BatchConfig          `yaml:"e_ncb"`
+	Masking             MaskingConfig        `yaml:"masking"`
+	Redaction           RedactionConfig      `yaml:"redaction"`
+	TemplateFile        string               `yaml:"template_file"`
+	DataPath            string               `yaml:"data_path"`
+	RunRetentionDays    int                  `yaml:"run_retention_days"`
+	Dispatcher          DispatcherConfig     `yaml:"dispatcher"`
+	RequestIDNode       *int                 `yaml:"request_id_node"`
+	ShutdownGracePeriod time.Duration        `yaml:"shutdown_grace_period"`
+	Timezone            string               `yaml:"timezone"`
+	OpsAPI              OpsAPIConfig         `yaml:"ops_api"`
+	LeaderElection      LeaderElectionConfig `yaml:"leader_election"`
+	LogPath             string               `yaml:"log_path"`
+	APILogPrefix        string               `yaml:"api_log_prefix"`
 }
 
 // LoadConfig loads the configuration from the specified environment's YAML file.
@@ -81,20 +215,98 @@ func loadConfigFromFile(env string) {
 		return
 	}
 
+	// Secrets come from the environment so they are not committed with the config files.
+	if salt := os.Getenv("REDACTION_HASH_SALT"); salt != "" {
+		config.Redaction.HashSalt = salt
+	}
+	if keys := os.Getenv("OPS_API_KEYS"); keys != "" {
+		config.OpsAPI.APIKeys = nil
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				config.OpsAPI.APIKeys = append(config.OpsAPI.APIKeys, key)
+			}
+		}
+	}
+
+	if node := os.Getenv("REQUEST_ID_NODE"); node != "" {
+		n, err := strconv.Atoi(node)
+		if err != nil {
+			log.Fatalf("Invalid REQUEST_ID_NODE '%s': %v", node, err)
+			return
+		}
+		config.RequestIDNode = &n
+	}
+	if config.RequestIDNode == nil {
+		log.Fatalf("request_id_node is required in config file '%s'; set a unique value per instance through REQUEST_ID_NODE", filename)
+		return
+	}
+
+	if config.ShutdownGracePeriod <= 0 {
+		config.ShutdownGracePeriod = 30
+	}
+
+	if config.RunRetentionDays <= 0 {
+		config.RunRetentionDays = 90
+	}
+	// Delivered notifications are kept as long as the run history that reports them.
+	if config.Dispatcher.SentRetentionDays <= 0 {
+		config.Dispatcher.SentRetentionDays = config.RunRetentionDays
+	}
+
+	// Without attempts every notification would be dead-lettered after its first failure.
+	if config.Dispatcher.MaxAttempts <= 0 {
+		config.Dispatcher.MaxAttempts = 5
+	}
+
+	if config.Masking.CardNoFormat == "" {
+		config.Masking.CardNoFormat = util.CardMaskLast4
+	}
+	if !util.IsValidCardMaskFormat(config.Masking.CardNoFormat) {
+		log.Fatalf("Invalid masking.card_no_format '%s' in config file '%s'", config.Masking.CardNoFormat, filename)
+		return
+	}
+
+	if config.Timezone != "" {
+		if _, err := time.LoadLocation(config.Timezone); err != nil {
+			log.Fatalf("Invalid timezone '%s' in config file '%s': %v", config.Timezone, filename, err)
+			return
+		}
+	}
+
 	cfgCache["default"] = &config
 	cfgCache["spending_alert"] = &Config{
-		Environment:   config.Environment,
-		APIEndpoints:  config.APIEndpoints,
-		SpendingAlert: config.SpendingAlert,
-		LogPath:       config.LogPath,
-		APILogPrefix:  config.APILogPrefix,
+		Environment:         config.Environment,
+		APIEndpoints:        config.APIEndpoints,
+		SpendingAlert:       config.SpendingAlert,
+		Masking:             config.Masking,
+		Redaction:           config.Redaction,
+		TemplateFile:        config.TemplateFile,
+		DataPath:            config.DataPath,
+		Dispatcher:          config.Dispatcher,
+		RequestIDNode:       config.RequestIDNode,
+		ShutdownGracePeriod: config.ShutdownGracePeriod,
+		Timezone:            config.Timezone,
+		OpsAPI:              config.OpsAPI,
+		LeaderElection:      config.LeaderElection,
+		LogPath:             config.LogPath,
+		APILogPrefix:        config.APILogPrefix,
 	}
 	cfgCache["encb"] = &Config{
-		Environment:  config.Environment,
-		APIEndpoints: config.APIEndpoints,
-		ENCB:         config.ENCB,
-		LogPath:      config.LogPath,
-		APILogPrefix: config.APILogPrefix,
+		Environment:         config.Environment,
+		APIEndpoints:        config.APIEndpoints,
+		ENCB:                config.ENCB,
+		Masking:             config.Masking,
+		Redaction:           config.Redaction,
+		TemplateFile:        config.TemplateFile,
+		DataPath:            config.DataPath,
+		Dispatcher:          config.Dispatcher,
+		RequestIDNode:       config.RequestIDNode,
+		ShutdownGracePeriod: config.ShutdownGracePeriod,
+		Timezone:            config.Timezone,
+		OpsAPI:              config.OpsAPI,
+		LeaderElection:      config.LeaderElection,
+		LogPath:             config.LogPath,
+		APILogPrefix:        config.APILogPrefix,
 	}
 }
 
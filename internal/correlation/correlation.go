@@ -0,0 +1,53 @@
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// HTTP headers used to propagate IDs to external APIs.
+const (
+	HeaderRunID         = "X-Run-ID"
+	HeaderCorrelationID = "X-Correlation-ID"
+)
+
+type contextKey int
+
+const (
+	runIDKey contextKey = iota
+	correlationIDKey
+)
+
+// NewRunID returns a new run ID for a batch, e.g. "spending_alert-20240102150405-1a2b3c4d".
+func NewRunID(batch string) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%s", batch, time.Now().Format("20060102150405.000000"))
+	}
+	return fmt.Sprintf("%s-%s-%s", batch, time.Now().Format("20060102150405"), hex.EncodeToString(b))
+}
+
+// WithRunID returns a copy of ctx carrying the batch run ID.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey, runID)
+}
+
+// RunID returns the batch run ID carried by ctx, or "".
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey).(string)
+	return id
+}
+
+// WithCorrelationID returns a copy of ctx carrying the per-record correlation ID.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationID returns the per-record correlation ID carried by ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
@@ -0,0 +1,239 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"notification_batch/internal/api"
+	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/queue"
+)
+
+var (
+	dispatcher *Dispatcher
+	once       sync.Once
+)
+
+// pruneInterval is how often the leader removes delivered notifications past their retention.
+const pruneInterval = time.Hour
+
+// Dispatcher drains the outbound notification queue with a pool of workers, retrying failed sends.
+type Dispatcher struct {
+	cfg      *config.Config
+	store    *queue.Store
+	client   *api.NotificationClient
+	jobs     chan *queue.Notification
+	inFlight map[string]bool
+	mu       sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	pruned   time.Time
+
+	// ctx is the parent of every send; cancel aborts in-flight HTTP calls when the shutdown
+	// grace period runs out.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// InitDispatcher initializes the dispatcher for the outbound notification queue.
+func InitDispatcher(cfg *config.Config) error {
+	var err error
+	once.Do(func() {
+		var store *queue.Store
+		store, err = queue.Open(queue.OutboxPath(cfg.DataPath))
+		if err != nil {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		dispatcher = &Dispatcher{
+			cfg:      cfg,
+			store:    store,
+			client:   api.NewNotificationClient(cfg),
+			jobs:     make(chan *queue.Notification),
+			inFlight: make(map[string]bool),
+			stop:     make(chan struct{}),
+			ctx:      ctx,
+			cancel:   cancel,
+		}
+	})
+	return err
+}
+
+// StartDispatcher starts the polling loop and worker pool in the background. Only the leader
+// polls the queue, so replicas sharing the data path do not send the same notification.
+func StartDispatcher() {
+	if dispatcher == nil {
+		logger.AppLogger.Warn("Dispatcher not initialized.")
+		return
+	}
+	workers := dispatcher.cfg.Dispatcher.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		dispatcher.wg.Add(1)
+		go dispatcher.work()
+	}
+	dispatcher.wg.Add(1)
+	go dispatcher.poll()
+	logger.AppLogger.Sugar().Infof("Dispatcher started with %d workers.", workers)
+}
+
+// StopDispatcher stops polling and waits for in-flight sends to finish. If shutdownCtx expires
+// first, the remaining sends are cancelled and an error is returned; cancelled notifications
+// stay pending and are sent after the next start.
+func StopDispatcher(shutdownCtx context.Context) error {
+	if dispatcher == nil {
+		logger.AppLogger.Warn("Dispatcher not initialized.")
+		return nil
+	}
+	d := dispatcher
+	close(d.stop)
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logger.AppLogger.Info("Dispatcher stopped.")
+		return nil
+	case <-shutdownCtx.Done():
+		d.cancel()
+		return fmt.Errorf("timed out waiting for in-flight sends, cancelled them: %v", shutdownCtx.Err())
+	}
+}
+
+func (d *Dispatcher) poll() {
+	defer d.wg.Done()
+	defer close(d.jobs)
+
+	interval := d.cfg.Dispatcher.PollInterval * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	select {
+	case <-leader.Elected():
+	case <-d.stop:
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !leader.IsLeader() {
+			// Leadership was given up on shutdown; the new leader sends what is left.
+			return
+		}
+		if time.Since(d.pruned) >= pruneInterval {
+			d.prune()
+		}
+		due, err := d.store.Due(time.Now())
+		if err != nil {
+			logger.AppLogger.Sugar().Errorf("Failed to load due notifications: %v", err)
+		}
+		for _, n := range due {
+			if !d.claim(n.ID) {
+				continue
+			}
+			select {
+			case d.jobs <- n:
+			case <-d.stop:
+				d.release(n.ID)
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for n := range d.jobs {
+		d.send(n)
+		d.release(n.ID)
+	}
+}
+
+func (d *Dispatcher) send(n *queue.Notification) {
+	ctx := correlation.WithCorrelationID(correlation.WithRunID(d.ctx, n.RunID), n.CorrelationID)
+	log := logger.FromContext(ctx)
+
+	response, err := d.client.SendNotification(ctx, n.Request)
+	if err != nil && ctx.Err() != nil {
+		log.Warnw("Notification send cancelled, left pending", "id", n.ID, "batch", n.Batch, "error", err)
+		return
+	}
+	if err == nil {
+		log.Infow("Notification sent", "id", n.ID, "batch", n.Batch, "user_token", n.Request.Usertoken, "response", response)
+		if err := d.store.Complete(n, response); err != nil {
+			log.Errorf("Failed to record sent notification '%s': %v", n.ID, err)
+		}
+		return
+	}
+
+	if !api.IsRetryable(err) || n.Attempts+1 >= d.cfg.Dispatcher.MaxAttempts {
+		log.Errorw("Notification failed permanently", "id", n.ID, "batch", n.Batch, "user_token", n.Request.Usertoken, "attempts", n.Attempts+1, "error", err)
+		if err := d.store.Fail(n, response, err); err != nil {
+			log.Errorf("Failed to record failed notification '%s': %v", n.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(d.backoff(n.Attempts + 1))
+	log.Warnw("Notification send failed, will retry", "id", n.ID, "batch", n.Batch, "user_token", n.Request.Usertoken, "attempts", n.Attempts+1, "next_attempt", next, "error", err)
+	if err := d.store.Retry(n, response, err, next); err != nil {
+		log.Errorf("Failed to reschedule notification '%s': %v", n.ID, err)
+	}
+}
+
+// prune removes delivered notifications older than the retention period; dead letters are kept until replayed.
+func (d *Dispatcher) prune() {
+	d.pruned = time.Now()
+	if d.cfg.Dispatcher.SentRetentionDays <= 0 {
+		return
+	}
+	retention := time.Duration(d.cfg.Dispatcher.SentRetentionDays) * 24 * time.Hour
+	removed, err := d.store.Prune(queue.StateSent, d.pruned.Add(-retention))
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to prune sent notifications: %v", err)
+	} else if removed > 0 {
+		logger.AppLogger.Sugar().Infof("Removed %d sent notifications older than %s.", removed, retention)
+	}
+}
+
+// backoff returns the delay before the next attempt, doubling the configured base per attempt.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.Dispatcher.RetryBackoff * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+func (d *Dispatcher) claim(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inFlight[id] {
+		return false
+	}
+	d.inFlight[id] = true
+	return true
+}
+
+func (d *Dispatcher) release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, id)
+}
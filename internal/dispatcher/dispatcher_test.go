@@ -0,0 +1,209 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"notification_batch/internal/api"
+	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/model"
+	"notification_batch/internal/queue"
+)
+
+// respond returns a fake Notification API handler answering with status and response code.
+func respond(status int, code string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(model.NotificationResponse{ResponseCode: code})
+	}
+}
+
+// newTestDispatcher returns a dispatcher sending to a fake Notification API served by handler.
+func newTestDispatcher(t *testing.T, handler http.HandlerFunc) *Dispatcher {
+	t.Helper()
+	logger.AppLogger = zap.NewNop()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{LogPath: t.TempDir(), APILogPrefix: "api"}
+	cfg.APIEndpoints.SendNotification = server.URL
+	cfg.APIEndpoints.Timeout = 5
+	cfg.APIEndpoints.ResponseCodes.Notification = config.ResponseCodeMapping{
+		Success:   []string{"0000"},
+		Retryable: []string{"9001"},
+		Permanent: []string{"1001"},
+	}
+	cfg.Dispatcher.MaxAttempts = 5
+	cfg.Dispatcher.RetryBackoff = 30
+
+	store, err := queue.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Dispatcher{
+		cfg:      cfg,
+		store:    store,
+		client:   api.NewNotificationClient(cfg),
+		jobs:     make(chan *queue.Notification),
+		inFlight: make(map[string]bool),
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: &config.Config{}}
+	d.cfg.Dispatcher.RetryBackoff = 30
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 4: 4 * time.Minute} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		code         string
+		attempts     int
+		wantState    string
+		wantAttempts int
+		wantDelay    time.Duration
+	}{
+		{"success", http.StatusOK, "0000", 0, queue.StateSent, 1, 0},
+		{"retryable status", http.StatusServiceUnavailable, "", 0, queue.StatePending, 1, 30 * time.Second},
+		{"retryable code doubles the backoff", http.StatusOK, "9001", 2, queue.StatePending, 3, 2 * time.Minute},
+		{"max attempts reached", http.StatusServiceUnavailable, "", 4, queue.StateDeadLetter, 5, 0},
+		{"non-retryable status", http.StatusBadRequest, "", 0, queue.StateDeadLetter, 1, 0},
+		{"permanent code", http.StatusOK, "1001", 0, queue.StateDeadLetter, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDispatcher(t, respond(tt.status, tt.code))
+			n := &queue.Notification{Batch: "spending_alert", Attempts: tt.attempts}
+			if err := d.store.Enqueue(n); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			d.send(n)
+			end := time.Now()
+
+			got, err := d.store.Get(tt.wantState, n.ID)
+			if err != nil {
+				t.Fatalf("Get(%s) error = %v", tt.wantState, err)
+			}
+			if got.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", got.Attempts, tt.wantAttempts)
+			}
+			if tt.wantState == queue.StatePending {
+				if got.NotBefore.Before(start.Add(tt.wantDelay)) || got.NotBefore.After(end.Add(tt.wantDelay)) {
+					t.Errorf("NotBefore = %s, want %s after the attempt", got.NotBefore, tt.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestSendPropagatesCorrelationID(t *testing.T) {
+	var header http.Header
+	var body model.NotificationRequest
+	d := newTestDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&body)
+		respond(http.StatusOK, "0000")(w, r)
+	})
+	core, logs := observer.New(zap.InfoLevel)
+	logger.AppLogger = zap.New(core)
+
+	n := &queue.Notification{Batch: "spending_alert", RunID: "spending_alert-run-1", CorrelationID: "corr-0001"}
+	if err := d.store.Enqueue(n); err != nil {
+		t.Fatal(err)
+	}
+	d.send(n)
+
+	if got := header.Get(correlation.HeaderRunID); got != n.RunID {
+		t.Errorf("%s header = %q, want %q", correlation.HeaderRunID, got, n.RunID)
+	}
+	if got := header.Get(correlation.HeaderCorrelationID); got != n.CorrelationID {
+		t.Errorf("%s header = %q, want %q", correlation.HeaderCorrelationID, got, n.CorrelationID)
+	}
+	if body.CorrelationID != n.CorrelationID {
+		t.Errorf("request correlation_id = %q, want %q", body.CorrelationID, n.CorrelationID)
+	}
+	sent := logs.FilterMessage("Notification sent").All()
+	if len(sent) != 1 {
+		t.Fatalf("logged %d 'Notification sent' entries, want 1", len(sent))
+	}
+	fields := sent[0].ContextMap()
+	if fields["run_id"] != n.RunID || fields["correlation_id"] != n.CorrelationID {
+		t.Errorf("log context = %v, want run_id %q and correlation_id %q", fields, n.RunID, n.CorrelationID)
+	}
+}
+
+func TestStopDispatcherDrainsInFlightSends(t *testing.T) {
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		wantErr   bool
+		wantState string
+	}{
+		{"send finishes within the grace period", time.Minute, false, queue.StateSent},
+		{"grace period runs out", 50 * time.Millisecond, true, queue.StatePending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan struct{})
+			release := make(chan struct{})
+			d := newTestDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+				close(received)
+				<-release
+				respond(http.StatusOK, "0000")(w, r)
+			})
+			// Unblock the fake API before the server is closed.
+			t.Cleanup(func() { close(release) })
+			defer func(previous *Dispatcher) { dispatcher = previous }(dispatcher)
+			dispatcher = d
+			n := &queue.Notification{Batch: "spending_alert"}
+			if err := d.store.Enqueue(n); err != nil {
+				t.Fatal(err)
+			}
+
+			StartDispatcher()
+			<-received
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), tt.grace)
+			defer cancel()
+			stopped := make(chan error, 1)
+			go func() { stopped <- StopDispatcher(shutdownCtx) }()
+
+			if !tt.wantErr {
+				select {
+				case err := <-stopped:
+					t.Fatalf("StopDispatcher() returned %v while a send was in flight", err)
+				case <-time.After(50 * time.Millisecond):
+				}
+				release <- struct{}{}
+			}
+			if err := <-stopped; (err != nil) != tt.wantErr {
+				t.Fatalf("StopDispatcher() error = %v, want error %v", err, tt.wantErr)
+			}
+			d.wg.Wait()
+			if _, err := d.store.Get(tt.wantState, n.ID); err != nil {
+				t.Errorf("Get(%s) error = %v", tt.wantState, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package ftp
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
 
 	"github.com/jlaffaye/ftp"
 )
@@ -20,15 +22,22 @@ type Config struct {
 	Password string
 }
 
+// FileInfo describes a remote file.
+type FileInfo struct {
+	Name    string
+	Size    uint64
+	ModTime time.Time
+}
+
 // Client wraps the ftp.ServerConn.
 type Client struct {
 	conn   *ftp.ServerConn
 	config Config
 }
 
-// NewClient creates a new FTP client connection.
-func NewClient(cfg Config) (*Client, error) {
-	conn, err := ftp.Dial(cfg.Host, ftp.DialWithTimeout(5*time.Second))
+// NewClient creates a new FTP client connection; ctx bounds the dial.
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	conn, err := ftp.Dial(cfg.Host, ftp.DialWithTimeout(5*time.Second), ftp.DialWithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to FTP server '%s': %v", cfg.Host, err)
 	}
@@ -61,39 +70,73 @@ func (c *Client) Close() {
 }
 
 // ListFiles lists files in the specified remote directory.
-func (c *Client) ListFiles(remotePath string) ([]string, error) {
+func (c *Client) ListFiles(ctx context.Context, remotePath string) ([]string, error) {
+	infos, err := c.ListFileInfos(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, info := range infos {
+		files = append(files, info.Name)
+	}
+
+	logger.AppLogger.Sugar().Infof("Found %d files in '%s'", len(files), remotePath)
+	return files, nil
+}
+
+// ListFileInfos lists files in the specified remote directory with their size and modification time.
+func (c *Client) ListFileInfos(ctx context.Context, remotePath string) ([]FileInfo, error) {
 	if c.conn == nil {
 		return nil, fmt.Errorf("FTP connection is not established")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	entries, err := c.conn.List(remotePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files in '%s': %v", remotePath, err)
 	}
 
-	var files []string
+	var infos []FileInfo
 	for _, entry := range entries {
 		if entry.Type == ftp.EntryTypeFile {
-			files = append(files, entry.Name)
+			infos = append(infos, FileInfo{Name: entry.Name, Size: entry.Size, ModTime: entry.Time})
 		}
 	}
-
-	logger.AppLogger.Sugar().Infof("Found %d files in '%s'", len(files), remotePath)
-	return files, nil
+	return infos, nil
 }
 
 // DownloadFile downloads a file from the remote path to the local directory.
-func (c *Client) DownloadFile(remotePath, localDir string) (localFilePath string, err error) {
+// Cancelling ctx aborts the transfer and removes the partial local file.
+func (c *Client) DownloadFile(ctx context.Context, remotePath, localDir string) (localFilePath string, err error) {
 	if c.conn == nil {
 		return "", fmt.Errorf("FTP connection is not established")
 	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
+	start := time.Now()
 	resp, err := c.conn.Retr(remotePath)
 	if err != nil {
+		metrics.ObserveFTPTransfer(metrics.FTPDownload, start, 0, err)
 		return "", fmt.Errorf("failed to retrieve file '%s': %v", remotePath, err)
 	}
 	defer resp.Close()
 
+	// Unblock a pending read on the data connection when ctx is cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			resp.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
 	fileName := filepath.Base(remotePath)
 	localFilePath = filepath.Join(localDir, fileName)
 
@@ -103,9 +146,14 @@ func (c *Client) DownloadFile(remotePath, localDir string) (localFilePath string
 	}
 	defer outFile.Close()
 
-	_, err = io.Copy(outFile, resp)
+	n, err := io.Copy(outFile, &contextReader{ctx: ctx, r: resp})
+	metrics.ObserveFTPTransfer(metrics.FTPDownload, start, n, err)
 	if err != nil {
+		outFile.Close()
 		os.Remove(localFilePath)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		return "", fmt.Errorf("failed to copy data from '%s' to '%s': %v", remotePath, localFilePath, err)
 	}
 
@@ -114,10 +162,14 @@ func (c *Client) DownloadFile(remotePath, localDir string) (localFilePath string
 }
 
 // UploadFile uploads a local file to the remote path.
-func (c *Client) UploadFile(localPath, remotePath string) error {
+// Cancelling ctx stops sending further data; the server may keep a partial file.
+func (c *Client) UploadFile(ctx context.Context, localPath, remotePath string) error {
 	if c.conn == nil {
 		return fmt.Errorf("FTP connection is not established")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	file, err := os.Open(localPath)
 	if err != nil {
@@ -125,11 +177,34 @@ func (c *Client) UploadFile(localPath, remotePath string) error {
 	}
 	defer file.Close()
 
-	err = c.conn.Stor(remotePath, file)
+	start := time.Now()
+	reader := &contextReader{ctx: ctx, r: file}
+	err = c.conn.Stor(remotePath, reader)
+	metrics.ObserveFTPTransfer(metrics.FTPUpload, start, reader.n, err)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("failed to store file '%s' to '%s': %v", localPath, remotePath, err)
 	}
 
 	logger.AppLogger.Sugar().Infof("Uploaded '%s' to FTP as '%s'", localPath, remotePath)
 	return nil
 }
+
+// contextReader fails reads once its context is done, so copies stop between chunks.
+// It counts the bytes read in n.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
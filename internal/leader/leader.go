@@ -0,0 +1,170 @@
+package leader
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/lock"
+	"notification_batch/internal/logger"
+)
+
+// Backends for the leader lock.
+const (
+	BackendFile = "file"
+)
+
+// ErrNotLeader is returned when an action reserved to the leader is requested from another replica.
+var ErrNotLeader = errors.New("this replica is not the leader")
+
+var (
+	elector *Elector
+	once    sync.Once
+
+	// always is closed for replicas that are always the leader.
+	always = make(chan struct{})
+)
+
+func init() {
+	close(always)
+}
+
+// Backend is a lock held by at most one replica at a time and released when its holder dies.
+type Backend interface {
+	TryLock() (bool, error)
+	Unlock() error
+}
+
+// Elector campaigns for leadership of this replica until it holds the backend lock.
+type Elector struct {
+	backend  Backend
+	interval time.Duration
+	leader   atomic.Bool
+	elected  chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// InitElector initializes leader election; when it is disabled this replica is always the leader.
+func InitElector(cfg *config.Config) error {
+	var err error
+	once.Do(func() {
+		le := cfg.LeaderElection
+		if !le.Enabled {
+			return
+		}
+		var backend Backend
+		backend, err = newBackend(le, cfg.DataPath)
+		if err != nil {
+			return
+		}
+		interval := le.RetryInterval * time.Second
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		elector = newElector(backend, interval)
+	})
+	return err
+}
+
+func newElector(backend Backend, interval time.Duration) *Elector {
+	return &Elector{
+		backend:  backend,
+		interval: interval,
+		elected:  make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func newBackend(le config.LeaderElectionConfig, dataPath string) (Backend, error) {
+	switch le.Backend {
+	case "", BackendFile:
+		path := le.LockPath
+		if path == "" {
+			path = filepath.Join(dataPath, "leader.lock")
+		}
+		return lock.New(path), nil
+	default:
+		return nil, fmt.Errorf("unsupported leader_election.backend '%s', expected '%s'", le.Backend, BackendFile)
+	}
+}
+
+// StartElector starts campaigning in the background.
+func StartElector() {
+	if elector == nil {
+		logger.AppLogger.Info("Leader election disabled; this replica runs scheduled jobs.")
+		return
+	}
+	go elector.campaign()
+}
+
+// StopElector stops campaigning and gives up leadership so another replica can take over.
+func StopElector() error {
+	if elector == nil {
+		return nil
+	}
+	return elector.resign()
+}
+
+// resign stops campaigning and releases the backend lock if it is held.
+func (e *Elector) resign() error {
+	close(e.stop)
+	<-e.done
+	if !e.leader.Load() {
+		return nil
+	}
+	e.leader.Store(false)
+	if err := e.backend.Unlock(); err != nil {
+		return err
+	}
+	logger.AppLogger.Info("Gave up leadership.")
+	return nil
+}
+
+// IsLeader reports whether this replica is the leader.
+func IsLeader() bool {
+	if elector == nil {
+		return true
+	}
+	return elector.leader.Load()
+}
+
+// Elected returns a channel closed once this replica is the leader.
+func Elected() <-chan struct{} {
+	if elector == nil {
+		return always
+	}
+	return elector.elected
+}
+
+// campaign tries to take the lock until it succeeds; the lock is then held until StopElector
+// or the process dies, which is when a standby replica takes over.
+func (e *Elector) campaign() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	logger.AppLogger.Info("Waiting to become the leader...")
+	for {
+		ok, err := e.backend.TryLock()
+		if err != nil {
+			logger.AppLogger.Sugar().Errorf("Leader election failed: %v", err)
+		}
+		if ok {
+			e.leader.Store(true)
+			close(e.elected)
+			logger.AppLogger.Info("Became the leader; running scheduled jobs.")
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-e.stop:
+			return
+		}
+	}
+}
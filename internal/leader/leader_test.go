@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification_batch/internal/lock"
+	"notification_batch/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+func waitElected(e *Elector, timeout time.Duration) bool {
+	select {
+	case <-e.elected:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestTakeover(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	first := newElector(lock.New(path), 10*time.Millisecond)
+	second := newElector(lock.New(path), 10*time.Millisecond)
+
+	go first.campaign()
+	if !waitElected(first, time.Second) || !first.leader.Load() {
+		t.Fatal("first replica was not elected")
+	}
+	go second.campaign()
+	if waitElected(second, 100*time.Millisecond) || second.leader.Load() {
+		t.Fatal("second replica was elected while the first holds the lock")
+	}
+
+	if err := first.resign(); err != nil {
+		t.Fatal(err)
+	}
+	if first.leader.Load() {
+		t.Error("first replica is still the leader after resigning")
+	}
+	if !waitElected(second, time.Second) || !second.leader.Load() {
+		t.Fatal("second replica did not take over")
+	}
+	if err := second.resign(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResignWithoutLeadership(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	holder := lock.New(path)
+	if ok, err := holder.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+	defer holder.Unlock()
+
+	standby := newElector(lock.New(path), 10*time.Millisecond)
+	go standby.campaign()
+	time.Sleep(50 * time.Millisecond)
+	if err := standby.resign(); err != nil {
+		t.Fatal(err)
+	}
+	if standby.leader.Load() {
+		t.Error("standby became the leader while the lock was held")
+	}
+}
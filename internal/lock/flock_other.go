@@ -0,0 +1,14 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// tryLockFile only creates the lock file; without flock the lock is held within the process only.
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often Lock retries a file lock held by another process.
+const pollInterval = time.Second
+
+// Lock allows one holder at a time, within the process and, through an advisory
+// file lock, across processes sharing the same lock file.
+type Lock struct {
+	path string
+	held chan struct{}
+	file *os.File
+}
+
+// New returns the lock backed by the file at path; the file is created on first use.
+func New(path string) *Lock {
+	return &Lock{path: path, held: make(chan struct{}, 1)}
+}
+
+// TryLock takes the lock if it is free and reports whether it did.
+func (l *Lock) TryLock() (bool, error) {
+	select {
+	case l.held <- struct{}{}:
+	default:
+		return false, nil
+	}
+	ok, err := l.lockFile()
+	if !ok || err != nil {
+		<-l.held
+	}
+	return ok, err
+}
+
+// Lock waits until the lock is free and takes it, or returns ctx's error.
+func (l *Lock) Lock(ctx context.Context) error {
+	select {
+	case l.held <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for {
+		ok, err := l.lockFile()
+		if err != nil || ok {
+			if err != nil {
+				<-l.held
+			}
+			return err
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			<-l.held
+			return ctx.Err()
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	defer func() { <-l.held }()
+	file := l.file
+	l.file = nil
+	if file == nil {
+		return nil
+	}
+	err := unlockFile(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release lock '%s': %v", l.path, err)
+	}
+	return nil
+}
+
+// lockFile takes the file lock without waiting; the in-process lock must be held.
+func (l *Lock) lockFile() (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create lock directory '%s': %v", filepath.Dir(l.path), err)
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock '%s': %v", l.path, err)
+	}
+	ok, err := tryLockFile(file)
+	if !ok || err != nil {
+		file.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to take lock '%s': %v", l.path, err)
+		}
+		return false, nil
+	}
+	l.file = file
+	return true, nil
+}
@@ -0,0 +1,90 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	l := New(path)
+	// other shares the lock file, like a second process.
+	other := New(path)
+
+	if ok, err := l.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() = %v, %v, want the free lock", ok, err)
+	}
+	if ok, err := l.TryLock(); ok || err != nil {
+		t.Errorf("TryLock() of a held lock = %v, %v", ok, err)
+	}
+	if ok, err := other.TryLock(); ok || err != nil {
+		t.Errorf("TryLock() through the shared file = %v, %v", ok, err)
+	}
+
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := other.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() after Unlock = %v, %v", ok, err)
+	}
+	if err := other.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	holder := New(path)
+	if ok, err := holder.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+
+	waiter := New(path)
+	locked := make(chan error, 1)
+	go func() {
+		locked <- waiter.Lock(context.Background())
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("Lock() returned %v while the lock is held", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := holder.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * pollInterval):
+		t.Fatal("Lock() did not take over the released lock")
+	}
+	if err := waiter.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	holder := New(path)
+	if ok, err := holder.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+	defer holder.Unlock()
+
+	waiter := New(path)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := waiter.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock() error = %v, want the context error", err)
+	}
+	// A cancelled wait leaves the lock usable.
+	if ok, err := waiter.TryLock(); ok || err != nil {
+		t.Errorf("TryLock() while held elsewhere = %v, %v", ok, err)
+	}
+}
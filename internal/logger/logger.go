@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"time"
 
+	"notification_batch/internal/correlation"
+	"notification_batch/internal/redact"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -13,8 +17,13 @@ import (
 // AppLogger is the global logger instance for the application.
 var AppLogger *zap.Logger
 
+// redactor is applied to every log sink before entries are written.
+var redactor *redact.Redactor
+
 // InitLogger initializes the application logger.
-func InitLogger(logPath string) error {
+func InitLogger(logPath string, r *redact.Redactor) error {
+	redactor = r
+
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(logPath, 0755); err != nil {
 			return err
@@ -39,7 +48,7 @@ func InitLogger(logPath string) error {
 	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.RFC3339)
 
 	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
+		newRedactingEncoder(zapcore.NewJSONEncoder(encoderConfig), redactor),
 		writeSyncer,
 		zap.InfoLevel,
 	)
@@ -52,12 +61,12 @@ func InitLogger(logPath string) error {
 }
 
 // ApiLogger logs API requests and responses to a separate file with timestamp.
-func ApiLogger(logPath, logPrefix, message string) {
+func ApiLogger(logPath, logPrefix, message string, fields ...zap.Field) {
 	apiLogFile := filepath.Join(logPath, logPrefix+".log")
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.RFC3339)
 	apiLogger := zap.New(zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
+		newRedactingEncoder(zapcore.NewJSONEncoder(encoderConfig), redactor),
 		zapcore.AddSync(&lumberjack.Logger{
 			Filename:   apiLogFile,
 			MaxSize:    100, // megabytes
@@ -68,5 +77,22 @@ func ApiLogger(logPath, logPrefix, message string) {
 		zap.InfoLevel,
 	))
 	defer apiLogger.Sync()
-	apiLogger.Info(message)
+	apiLogger.Info(message, fields...)
+}
+
+// ContextFields returns the run and correlation IDs carried by ctx as zap fields.
+func ContextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if runID := correlation.RunID(ctx); runID != "" {
+		fields = append(fields, zap.String("run_id", runID))
+	}
+	if correlationID := correlation.CorrelationID(ctx); correlationID != "" {
+		fields = append(fields, zap.String("correlation_id", correlationID))
+	}
+	return fields
+}
+
+// FromContext returns the application logger annotated with the IDs carried by ctx.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	return AppLogger.With(ContextFields(ctx)...).Sugar()
 }
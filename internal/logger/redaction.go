@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"notification_batch/internal/redact"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactingEncoder wraps a zapcore.Encoder and applies field redaction rules to every field,
+// whether added with With or logged with an entry, including reflected and nested values.
+type redactingEncoder struct {
+	zapcore.Encoder
+	redactor *redact.Redactor
+}
+
+func newRedactingEncoder(enc zapcore.Encoder, redactor *redact.Redactor) zapcore.Encoder {
+	return &redactingEncoder{Encoder: enc, redactor: redactor}
+}
+
+func (e *redactingEncoder) Clone() zapcore.Encoder {
+	return &redactingEncoder{Encoder: e.Encoder.Clone(), redactor: e.redactor}
+}
+
+// EncodeEntry adds the entry fields through the redacting methods before encoding the entry,
+// since the wrapped encoder would otherwise add them to itself directly.
+func (e *redactingEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	clone := e.Clone().(*redactingEncoder)
+	for _, f := range fields {
+		f.AddTo(clone)
+	}
+	return clone.Encoder.EncodeEntry(ent, nil)
+}
+
+// redacted writes the value of key according to its redaction rule and reports whether it did;
+// a key without a rule is left to the caller.
+func (e *redactingEncoder) redacted(key string, value func() string) bool {
+	switch action := e.redactor.Action(key); action {
+	case "":
+		return false
+	case redact.ActionDrop:
+		return true
+	default:
+		e.Encoder.AddString(key, e.redactor.Apply(action, value()))
+		return true
+	}
+}
+
+func (e *redactingEncoder) sprint(v interface{}) func() string {
+	return func() string { return fmt.Sprint(v) }
+}
+
+func (e *redactingEncoder) AddString(key, value string) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddString(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddByteString(key string, value []byte) {
+	if !e.redacted(key, func() string { return string(value) }) {
+		e.Encoder.AddByteString(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddBinary(key string, value []byte) {
+	if !e.redacted(key, func() string { return string(value) }) {
+		e.Encoder.AddBinary(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddReflected(key string, value interface{}) error {
+	if e.redacted(key, func() string { return e.redactor.String(value) }) {
+		return nil
+	}
+	return e.Encoder.AddReflected(key, e.redactor.Value(value))
+}
+
+// AddObject encodes obj into a map first so that rules apply to its nested fields.
+func (e *redactingEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddObject(key, obj); err != nil {
+		return err
+	}
+	return e.AddReflected(key, m.Fields[key])
+}
+
+// AddArray encodes arr into a slice first so that rules apply to its nested fields.
+func (e *redactingEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(key, arr); err != nil {
+		return err
+	}
+	return e.AddReflected(key, m.Fields[key])
+}
+
+func (e *redactingEncoder) AddBool(key string, value bool) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddBool(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddComplex128(key string, value complex128) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddComplex128(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddComplex64(key string, value complex64) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddComplex64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddDuration(key string, value time.Duration) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddDuration(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddFloat64(key string, value float64) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddFloat64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddFloat32(key string, value float32) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddFloat32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt(key string, value int) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddInt(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt64(key string, value int64) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddInt64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt32(key string, value int32) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddInt32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt16(key string, value int16) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddInt16(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt8(key string, value int8) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddInt8(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddTime(key string, value time.Time) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddTime(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint(key string, value uint) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUint(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint64(key string, value uint64) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUint64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint32(key string, value uint32) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUint32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint16(key string, value uint16) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUint16(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint8(key string, value uint8) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUint8(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUintptr(key string, value uintptr) {
+	if !e.redacted(key, e.sprint(value)) {
+		e.Encoder.AddUintptr(key, value)
+	}
+}
+
+// Redact returns v as JSON text with the configured redaction rules applied, for use in log messages.
+func Redact(v interface{}) string {
+	return redactor.String(v)
+}
+
+// RedactBody returns a raw HTTP body with the configured redaction rules applied, for use in log messages.
+func RedactBody(data []byte) string {
+	return redactor.Body(data)
+}
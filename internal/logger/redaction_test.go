@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/redact"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tokenObject struct {
+	token string
+}
+
+func (o tokenObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user_token", o.token)
+	enc.AddString("batch", "spending_alert")
+	return nil
+}
+
+func newTestLogger(t *testing.T, buf *bytes.Buffer) *zap.Logger {
+	t.Helper()
+	r, err := redact.New(config.RedactionConfig{
+		HashSalt: "salt",
+		Rules: []config.RedactionRule{
+			{Field: "user_token", Action: "hash"},
+			{Field: "pin", Action: "drop"},
+			{Field: "mobile", Action: "mask"},
+			{Field: "card_no", Action: "card"},
+		},
+	}, util.CardMaskLast4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := newRedactingEncoder(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), r)
+	return zap.New(zapcore.NewCore(enc, zapcore.AddSync(buf), zap.InfoLevel))
+}
+
+func TestRedactingEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      func(*zap.Logger)
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "string field",
+			log:      func(l *zap.Logger) { l.Info("msg", zap.String("user_token", "secret-token")) },
+			want:     []string{`"user_token":"sha256:`},
+			unwanted: []string{"secret-token"},
+		},
+		{
+			name:     "context field from With",
+			log:      func(l *zap.Logger) { l.With(zap.String("mobile", "0812345678")).Info("msg") },
+			want:     []string{`"mobile":"******5678"`},
+			unwanted: []string{"0812345678"},
+		},
+		{
+			name:     "dropped number",
+			log:      func(l *zap.Logger) { l.Info("msg", zap.Int("pin", 123456)) },
+			unwanted: []string{"pin", "123456"},
+		},
+		{
+			name:     "hashed number",
+			log:      func(l *zap.Logger) { l.Info("msg", zap.Int64("user_token", 987654321)) },
+			want:     []string{`"user_token":"sha256:`},
+			unwanted: []string{"987654321"},
+		},
+		{
+			name: "reflected struct",
+			log: func(l *zap.Logger) {
+				l.Info("msg", zap.Any("request", struct {
+					UserToken string `json:"user_token"`
+					CardNo    string `json:"card_no"`
+				}{"secret-token", "4111111111111111"}))
+			},
+			want:     []string{`"card_no":"************1111"`, `"user_token":"sha256:`},
+			unwanted: []string{"secret-token", "4111111111111111"},
+		},
+		{
+			name:     "object marshaler",
+			log:      func(l *zap.Logger) { l.Info("msg", zap.Object("setting", tokenObject{"secret-token"})) },
+			want:     []string{`"batch":"spending_alert"`, `"user_token":"sha256:`},
+			unwanted: []string{"secret-token"},
+		},
+		{
+			name: "array of objects",
+			log: func(l *zap.Logger) {
+				l.Info("msg", zap.Objects("settings", []tokenObject{{"secret-a"}, {"secret-b"}}))
+			},
+			unwanted: []string{"secret-a", "secret-b"},
+		},
+		{
+			name: "namespaced field",
+			log: func(l *zap.Logger) {
+				l.Info("msg", zap.Namespace("customer"), zap.String("user_token", "secret-token"))
+			},
+			unwanted: []string{"secret-token"},
+		},
+		{
+			name:     "sugared key values",
+			log:      func(l *zap.Logger) { l.Sugar().Infow("msg", "user_token", "secret-token", "error", errors.New("boom")) },
+			want:     []string{`"error":"boom"`},
+			unwanted: []string{"secret-token"},
+		},
+		{
+			name: "unrelated digit runs are kept",
+			log: func(l *zap.Logger) {
+				l.Info("Queued 20261019013009-3f2a9c1b", zap.String("id", "20261019013009-3f2a9c1b"))
+			},
+			want: []string{`"msg":"Queued 20261019013009-3f2a9c1b"`, `"id":"20261019013009-3f2a9c1b"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(newTestLogger(t, &buf))
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("log = %s, want it to contain %s", out, want)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("log = %s, want it not to contain %s", out, unwanted)
+				}
+			}
+		})
+	}
+}
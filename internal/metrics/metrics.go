@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "notification_batch"
+
+// External APIs, used as the api label.
+const (
+	APIAlertSetting = "alert_setting"
+	APINotification = "notification"
+)
+
+// FTP transfer directions, used as the direction label.
+const (
+	FTPDownload = "download"
+	FTPUpload   = "upload"
+)
+
+var (
+	records = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "records_total",
+		Help:      "Records processed by batch, job and outcome.",
+	}, []string{"batch", "job", "outcome"})
+
+	apiDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of external API calls by API and HTTP status code, or \"error\" if no response was received.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api", "status"})
+
+	ftpBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ftp_transfer_bytes_total",
+		Help:      "Bytes transferred over FTP by direction.",
+	}, []string{"direction"})
+
+	ftpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ftp_transfer_duration_seconds",
+		Help:      "Duration of FTP file transfers by direction and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"direction", "result"})
+
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of batch job runs by batch, job and final state.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 15),
+	}, []string{"batch", "job", "state"})
+
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run by batch and job.",
+	}, []string{"batch", "job"})
+)
+
+// Handler returns the HTTP handler exposing the metrics to Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// AddRecords counts n records of a batch job with the given outcome.
+func AddRecords(batch, job, outcome string, n int) {
+	records.WithLabelValues(batch, job, outcome).Add(float64(n))
+}
+
+// ObserveAPICall records an API call started at start; statusCode is 0 if no response was received.
+func ObserveAPICall(api string, start time.Time, statusCode int) {
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	apiDuration.WithLabelValues(api, status).Observe(time.Since(start).Seconds())
+}
+
+// ObserveFTPTransfer records an FTP transfer started at start that moved n bytes.
+func ObserveFTPTransfer(direction string, start time.Time, n int64, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	ftpBytes.WithLabelValues(direction).Add(float64(n))
+	ftpDuration.WithLabelValues(direction, result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveJobRun records a finished run of a batch job.
+func ObserveJobRun(batch, job, state string, d time.Duration) {
+	jobDuration.WithLabelValues(batch, job, state).Observe(d.Seconds())
+}
+
+// SetJobLastSuccess records t as the last successful run of a batch job.
+func SetJobLastSuccess(batch, job string, t time.Time) {
+	jobLastSuccess.WithLabelValues(batch, job).Set(float64(t.Unix()))
+}
@@ -2,16 +2,22 @@ package model
 
 // AlertSettingRequest defines the request structure for the Get Alert Setting API.
 type AlertSettingRequest struct {
-	RequestID string `json:"RequestID"`
-	UserToken string `json:"UserToken"`
+	RequestID     string `json:"RequestID"`
+	UserToken     string `json:"UserToken"`
+	CorrelationID string `json:"CorrelationID,omitempty"`
 }
 
 // AlertSettingResponse defines the response structure from the Get Alert Setting API.
 type AlertSettingResponse struct {
-	ResponseID        string `json:"ResponseID"`
-	ResponseCode      string `json:"ResponseCode"`
-	ResponseMessage   string `json:"ResponseMessage"`
-	UserToken         string `json:"UserToken"`
-	SpendingAlertFlag bool   `json:"spending_alert_flag"`
-	LastLogin         string `json:"last_login"`
+	ResponseID             string  `json:"ResponseID"`
+	ResponseCode           string  `json:"ResponseCode"`
+	ResponseMessage        string  `json:"ResponseMessage"`
+	UserToken              string  `json:"UserToken"`
+	SpendingAlertFlag      bool    `json:"spending_alert_flag"`
+	SpendingAlertThreshold float64 `json:"spending_alert_threshold"`
+	LastLogin              string  `json:"last_login"`
+	PreferredLanguage      string  `json:"preferred_language"`
+	PreferredChannel       string  `json:"preferred_channel"`
+	QuietHoursStart        string  `json:"quiet_hours_start"`
+	QuietHoursEnd          string  `json:"quiet_hours_end"`
 }
@@ -12,6 +12,7 @@ type NotificationRequest struct {
 	MessageinboxTH string `json:"messageinbox_th,omitempty"`
 	TitleinboxEN   string `json:"titleinbox_en,omitempty"`
 	MessageinboxEN string `json:"messageinbox_en,omitempty"`
+	CorrelationID  string `json:"correlation_id,omitempty"`
 }
 
 // NotificationResponse defines the response structure from the Send Notification API.
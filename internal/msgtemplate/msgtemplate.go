@@ -0,0 +1,189 @@
+package msgtemplate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"notification_batch/internal/logger"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	stores   = make(map[string]*Store)
+	storesMu sync.Mutex
+)
+
+// Message holds the rendered push and inbox texts for a single language.
+type Message struct {
+	Title        string
+	Message      string
+	TitleInbox   string
+	MessageInbox string
+}
+
+// messageDef is the YAML definition of a message template for a single language.
+type messageDef struct {
+	Title        string `yaml:"title"`
+	Message      string `yaml:"message"`
+	TitleInbox   string `yaml:"title_inbox"`
+	MessageInbox string `yaml:"message_inbox"`
+}
+
+type compiledMessage struct {
+	title        *template.Template
+	message      *template.Template
+	titleInbox   *template.Template
+	messageInbox *template.Template
+}
+
+// Store holds message templates keyed by topic and language, reloaded when the template file changes.
+type Store struct {
+	path      string
+	mu        sync.RWMutex
+	modTime   time.Time
+	templates map[string]map[string]*compiledMessage
+}
+
+// Get returns the template store for the given file, loading it on first use.
+func Get(path string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[path]; ok {
+		return s, nil
+	}
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	stores[path] = s
+	return s, nil
+}
+
+// Render renders the template for topic and language with data, reloading the file first if it has changed.
+func (s *Store) Render(topic, lang string, data interface{}) (Message, error) {
+	s.reloadIfModified()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byLang, ok := s.templates[topic]
+	if !ok {
+		return Message{}, fmt.Errorf("no template defined for topic '%s'", topic)
+	}
+	tmpl, ok := byLang[strings.ToLower(lang)]
+	if !ok {
+		return Message{}, fmt.Errorf("no template defined for topic '%s' and language '%s'", topic, lang)
+	}
+
+	var msg Message
+	var err error
+	if msg.Title, err = execute(tmpl.title, data); err != nil {
+		return Message{}, err
+	}
+	if msg.Message, err = execute(tmpl.message, data); err != nil {
+		return Message{}, err
+	}
+	if msg.TitleInbox, err = execute(tmpl.titleInbox, data); err != nil {
+		return Message{}, err
+	}
+	if msg.MessageInbox, err = execute(tmpl.messageInbox, data); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func (s *Store) reloadIfModified() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to stat template file '%s', keeping current templates: %v", s.path, err)
+		return
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	if err := s.load(); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to reload template file '%s', keeping current templates: %v", s.path, err)
+		return
+	}
+	logger.AppLogger.Sugar().Infof("Reloaded message templates from '%s'", s.path)
+}
+
+func (s *Store) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat template file '%s': %v", s.path, err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read template file '%s': %v", s.path, err)
+	}
+
+	var defs map[string]map[string]messageDef
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return fmt.Errorf("failed to unmarshal template file '%s': %v", s.path, err)
+	}
+
+	templates := make(map[string]map[string]*compiledMessage, len(defs))
+	for topic, byLang := range defs {
+		templates[topic] = make(map[string]*compiledMessage, len(byLang))
+		for lang, def := range byLang {
+			compiled, err := compile(topic+"."+lang, def)
+			if err != nil {
+				return err
+			}
+			templates[topic][strings.ToLower(lang)] = compiled
+		}
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+func compile(name string, def messageDef) (*compiledMessage, error) {
+	parse := func(field, text string) (*template.Template, error) {
+		t, err := template.New(name + "." + field).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template '%s.%s': %v", name, field, err)
+		}
+		return t, nil
+	}
+
+	var c compiledMessage
+	var err error
+	if c.title, err = parse("title", def.Title); err != nil {
+		return nil, err
+	}
+	if c.message, err = parse("message", def.Message); err != nil {
+		return nil, err
+	}
+	if c.titleInbox, err = parse("title_inbox", def.TitleInbox); err != nil {
+		return nil, err
+	}
+	if c.messageInbox, err = parse("message_inbox", def.MessageInbox); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template '%s': %v", t.Name(), err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,384 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"notification_batch/internal/model"
+	"notification_batch/internal/util"
+)
+
+const fileExt = ".json"
+
+// Notification states; each state is a subdirectory of the store.
+const (
+	StatePending    = "pending"
+	StateSent       = "sent"
+	StateDeadLetter = "dead_letter"
+)
+
+var states = []string{StatePending, StateSent, StateDeadLetter}
+
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// ErrNotFound is returned when a notification does not exist in the requested state.
+var ErrNotFound = errors.New("notification not found")
+
+// Attempt records the outcome of a single delivery attempt.
+type Attempt struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Notification is a notification request persisted for delivery by the dispatcher.
+type Notification struct {
+	ID            string                      `json:"id"`
+	Batch         string                      `json:"batch"`
+	RunID         string                      `json:"run_id"`
+	CorrelationID string                      `json:"correlation_id"`
+	SourceFile    string                      `json:"source_file"`
+	ResultColumns []string                    `json:"result_columns"`
+	Request       model.NotificationRequest   `json:"request"`
+	State         string                      `json:"state"`
+	NotBefore     time.Time                   `json:"not_before"`
+	Attempts      int                         `json:"attempts"`
+	LastError     string                      `json:"last_error,omitempty"`
+	History       []Attempt                   `json:"history,omitempty"`
+	Response      *model.NotificationResponse `json:"response,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+// ResultRow formats n as a delivery result line: its result columns followed by status,
+// gateway response code and detail, and the correlation ID when extra is set.
+func (n *Notification) ResultRow(extra bool) string {
+	var status, detail string
+	switch n.State {
+	case StateSent:
+		status = "Sent"
+		if n.Response != nil {
+			detail = n.Response.ResponseMessage
+		}
+	case StateDeadLetter:
+		status, detail = "Failed", n.LastError
+	default:
+		status, detail = "Pending", fmt.Sprintf("attempts=%d", n.Attempts)
+	}
+	responseCode := ""
+	if n.Response != nil {
+		responseCode = n.Response.ResponseCode
+	}
+	columns := append(append([]string{}, n.ResultColumns...), status, responseCode, strings.ReplaceAll(detail, ",", ";"))
+	if extra {
+		columns = append(columns, n.CorrelationID)
+	}
+	return strings.Join(columns, ",")
+}
+
+// Filter selects notifications when listing a state; zero fields match everything.
+type Filter struct {
+	Batch      string
+	RunID      string
+	SourceFile string
+	CreatedOn  time.Time
+}
+
+func (f Filter) matches(n *Notification) bool {
+	if f.Batch != "" && n.Batch != f.Batch {
+		return false
+	}
+	if f.RunID != "" && n.RunID != f.RunID {
+		return false
+	}
+	if f.SourceFile != "" && n.SourceFile != f.SourceFile {
+		return false
+	}
+	if !f.CreatedOn.IsZero() {
+		y1, m1, d1 := f.CreatedOn.Date()
+		y2, m2, d2 := n.CreatedAt.In(f.CreatedOn.Location()).Date()
+		if y1 != y2 || m1 != m2 || d1 != d2 {
+			return false
+		}
+	}
+	return true
+}
+
+// Store is a directory-backed notification queue, one JSON file per entry and one directory per state.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// OutboxPath returns the directory of the outbound notification queue under dataPath.
+func OutboxPath(dataPath string) string {
+	return filepath.Join(dataPath, "outbox")
+}
+
+// Open opens the store in dir, creating the state directories if needed.
+func Open(dir string) (*Store, error) {
+	for _, state := range states {
+		if err := os.MkdirAll(filepath.Join(dir, state), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory '%s': %v", filepath.Join(dir, state), err)
+		}
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Enqueue persists n as pending, assigning an ID and creation time if they are not set.
+func (s *Store) Enqueue(n *Notification) error {
+	if n.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		n.ID = id
+	}
+	now := time.Now()
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = now
+	}
+	n.UpdatedAt = now
+	n.State = StatePending
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(n)
+}
+
+// Due returns the pending notifications whose NotBefore time is at or before now, oldest first.
+func (s *Store) Due(now time.Time) ([]*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, err := s.readAll(StatePending)
+	if err != nil {
+		return nil, err
+	}
+	var due []*Notification
+	for _, n := range pending {
+		if !n.NotBefore.After(now) {
+			due = append(due, n)
+		}
+	}
+	return due, nil
+}
+
+// List returns the notifications in state that match filter, oldest first.
+func (s *Store) List(state string, filter Filter) ([]*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll(state)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*Notification
+	for _, n := range all {
+		if filter.matches(n) {
+			matched = append(matched, n)
+		}
+	}
+	return matched, nil
+}
+
+// Get returns the notification with the given ID in state.
+func (s *Store) Get(state, id string) (*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(state, id)
+}
+
+// Complete records a successful delivery and moves n to the sent state.
+func (s *Store) Complete(n *Notification, response *model.NotificationResponse) error {
+	n.recordAttempt(nil)
+	n.Response = response
+	n.LastError = ""
+	return s.move(n, StateSent)
+}
+
+// Retry records a failed attempt, with the gateway response if one was received, and keeps n pending until next.
+func (s *Store) Retry(n *Notification, response *model.NotificationResponse, cause error, next time.Time) error {
+	n.recordAttempt(cause)
+	n.Response = response
+	n.NotBefore = next
+	return s.move(n, StatePending)
+}
+
+// Fail records the final failed attempt, with the gateway response if one was received, and moves n to the dead-letter state.
+func (s *Store) Fail(n *Notification, response *model.NotificationResponse, cause error) error {
+	n.recordAttempt(cause)
+	n.Response = response
+	return s.move(n, StateDeadLetter)
+}
+
+// Replay moves a dead-lettered notification back to pending for immediate delivery.
+// The attempt counter is reset; the attempt history is kept.
+func (s *Store) Replay(id string) (*Notification, error) {
+	n, err := s.Get(StateDeadLetter, id)
+	if err != nil {
+		return nil, err
+	}
+	n.Attempts = 0
+	n.NotBefore = time.Now()
+	if err := s.move(n, StatePending); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Notification) recordAttempt(cause error) {
+	n.Attempts++
+	attempt := Attempt{At: time.Now()}
+	if cause != nil {
+		attempt.Error = cause.Error()
+		n.LastError = attempt.Error
+	}
+	n.History = append(n.History, attempt)
+}
+
+// Remove deletes the notification with the given ID from state.
+func (s *Store) Remove(state, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(state, id))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove queued notification '%s': %v", id, err)
+	}
+	return nil
+}
+
+// Prune removes the notifications in state last updated before cutoff and returns how many were removed.
+func (s *Store) Prune(state string, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, state)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read queue directory '%s': %v", dir, err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		// Every write replaces the file, so its modification time is the notification's UpdatedAt.
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to stat queued notification '%s': %v", entry.Name(), err)
+		}
+		if !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove queued notification '%s': %v", entry.Name(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *Store) move(n *Notification, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := n.State
+	n.State = state
+	n.UpdatedAt = time.Now()
+	if err := s.write(n); err != nil {
+		return err
+	}
+	if from != "" && from != state {
+		if err := os.Remove(s.path(from, n.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove '%s' notification '%s': %v", from, n.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) path(state, id string) string {
+	return filepath.Join(s.dir, state, id+fileExt)
+}
+
+// write stores n in its state directory atomically by writing a temporary file and renaming it.
+func (s *Store) write(n *Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queued notification '%s': %v", n.ID, err)
+	}
+	path := s.path(n.State, n.ID)
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write queued notification '%s': %v", n.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) read(state, id string) (*Notification, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(state, id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queued notification '%s': %v", id, err)
+	}
+	n := &Notification{}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queued notification '%s': %v", id, err)
+	}
+	return n, nil
+}
+
+func (s *Store) readAll(state string) ([]*Notification, error) {
+	dir := filepath.Join(s.dir, state)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory '%s': %v", dir, err)
+	}
+
+	var all []*Notification
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if os.IsNotExist(err) {
+			// Moved to another state since the directory was read.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queued notification '%s': %v", entry.Name(), err)
+		}
+		n := &Notification{}
+		if err := json.Unmarshal(data, n); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal queued notification '%s': %v", entry.Name(), err)
+		}
+		all = append(all, n)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	return all, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate queue ID: %v", err)
+	}
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"notification_batch/internal/model"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func enqueueTest(t *testing.T, s *Store, n *Notification) *Notification {
+	t.Helper()
+	if err := s.Enqueue(n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// assertState checks that id exists only in state.
+func assertState(t *testing.T, s *Store, id, state string) {
+	t.Helper()
+	for _, st := range states {
+		_, err := s.Get(st, id)
+		switch {
+		case st == state && err != nil:
+			t.Errorf("Get(%s, %s) error = %v, want the notification", st, id, err)
+		case st != state && !errors.Is(err, ErrNotFound):
+			t.Errorf("Get(%s, %s) error = %v, want ErrNotFound", st, id, err)
+		}
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	response := &model.NotificationResponse{ResponseCode: "0000", ResponseMessage: "Success"}
+	tests := []struct {
+		name         string
+		apply        func(s *Store, n *Notification) error
+		wantState    string
+		wantAttempts int
+		wantError    string
+	}{
+		{
+			name:      "enqueue",
+			apply:     func(s *Store, n *Notification) error { return nil },
+			wantState: StatePending,
+		},
+		{
+			name:         "complete",
+			apply:        func(s *Store, n *Notification) error { return s.Complete(n, response) },
+			wantState:    StateSent,
+			wantAttempts: 1,
+		},
+		{
+			name: "retry",
+			apply: func(s *Store, n *Notification) error {
+				return s.Retry(n, nil, errors.New("timeout"), time.Now().Add(time.Minute))
+			},
+			wantState:    StatePending,
+			wantAttempts: 1,
+			wantError:    "timeout",
+		},
+		{
+			name:         "fail",
+			apply:        func(s *Store, n *Notification) error { return s.Fail(n, nil, errors.New("rejected")) },
+			wantState:    StateDeadLetter,
+			wantAttempts: 1,
+			wantError:    "rejected",
+		},
+		{
+			name: "retry then complete",
+			apply: func(s *Store, n *Notification) error {
+				if err := s.Retry(n, nil, errors.New("timeout"), time.Now()); err != nil {
+					return err
+				}
+				return s.Complete(n, response)
+			},
+			wantState:    StateSent,
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			n := enqueueTest(t, s, &Notification{Batch: "spending_alert"})
+			if err := tt.apply(s, n); err != nil {
+				t.Fatal(err)
+			}
+			assertState(t, s, n.ID, tt.wantState)
+			got, err := s.Get(tt.wantState, n.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Attempts != tt.wantAttempts || len(got.History) != tt.wantAttempts {
+				t.Errorf("attempts = %d, history = %d, want %d", got.Attempts, len(got.History), tt.wantAttempts)
+			}
+			if got.LastError != tt.wantError {
+				t.Errorf("LastError = %q, want %q", got.LastError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Now()
+	due := enqueueTest(t, s, &Notification{NotBefore: now.Add(-time.Minute)})
+	enqueueTest(t, s, &Notification{NotBefore: now.Add(time.Hour)})
+	sent := enqueueTest(t, s, &Notification{})
+	if err := s.Complete(sent, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Due(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != due.ID {
+		t.Errorf("Due() = %v, want only %s", got, due.ID)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s := openTestStore(t)
+	n := enqueueTest(t, s, &Notification{})
+	for i := 0; i < 2; i++ {
+		if err := s.Retry(n, nil, errors.New("timeout"), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Fail(n, nil, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := s.Replay(n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertState(t, s, n.ID, StatePending)
+	if replayed.Attempts != 0 {
+		t.Errorf("Attempts = %d, want 0 after replay", replayed.Attempts)
+	}
+	if len(replayed.History) != 3 {
+		t.Errorf("History = %d entries, want the 3 earlier attempts kept", len(replayed.History))
+	}
+	if due, _ := s.Due(time.Now()); len(due) != 1 {
+		t.Errorf("Due() = %d notifications, want the replayed one", len(due))
+	}
+
+	if _, err := s.Replay(n.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Replay() error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Replay("../pending/" + n.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Replay() with a path error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	s := openTestStore(t)
+	day := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	enqueueTest(t, s, &Notification{Batch: "spending_alert", RunID: "run-1", CreatedAt: day})
+	enqueueTest(t, s, &Notification{Batch: "spending_alert", RunID: "run-2", CreatedAt: day.AddDate(0, 0, -1)})
+	enqueueTest(t, s, &Notification{Batch: "encb", RunID: "run-3", CreatedAt: day})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 3},
+		{"batch", Filter{Batch: "spending_alert"}, 2},
+		{"run", Filter{RunID: "run-2"}, 1},
+		{"created on", Filter{CreatedOn: day}, 2},
+		{"batch and day", Filter{Batch: "spending_alert", CreatedOn: day}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(StatePending, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("List() = %d notifications, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := openTestStore(t)
+	old := enqueueTest(t, s, &Notification{Batch: "spending_alert"})
+	recent := enqueueTest(t, s, &Notification{Batch: "spending_alert"})
+	for _, n := range []*Notification{old, recent} {
+		if err := s.Complete(n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cutoff := time.Now().Add(-time.Hour)
+	stale := cutoff.Add(-time.Minute)
+	if err := os.Chtimes(s.path(StateSent, old.ID), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	pending := enqueueTest(t, s, &Notification{Batch: "spending_alert"})
+	if err := os.Chtimes(s.path(StatePending, pending.ID), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Prune(StateSent, cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune() = %d, want 1", removed)
+	}
+	if _, err := s.Get(StateSent, old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(old) error = %v, want ErrNotFound", err)
+	}
+	assertState(t, s, recent.ID, StateSent)
+	assertState(t, s, pending.ID, StatePending)
+}
@@ -0,0 +1,146 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/util"
+)
+
+// Supported redaction actions.
+const (
+	ActionMask = "mask"
+	ActionHash = "hash"
+	ActionDrop = "drop"
+	ActionCard = "card"
+)
+
+const (
+	maskVisibleChars = 4
+	hashLength       = 16
+)
+
+// Redactor applies field rules to values before they are logged.
+// Free text is not scanned; card numbers are masked where they are parsed or through card field rules.
+type Redactor struct {
+	rules        map[string]string
+	hashSalt     string
+	cardNoFormat string
+}
+
+// New creates a Redactor from the redaction and masking configuration.
+func New(cfg config.RedactionConfig, cardNoFormat string) (*Redactor, error) {
+	rules := make(map[string]string, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		action := strings.ToLower(rule.Action)
+		if action != ActionMask && action != ActionHash && action != ActionDrop && action != ActionCard {
+			return nil, fmt.Errorf("invalid redaction action '%s' for field '%s'", rule.Action, rule.Field)
+		}
+		// Unsalted hashes of user tokens can be reversed with a dictionary.
+		if action == ActionHash && cfg.HashSalt == "" {
+			return nil, fmt.Errorf("redaction.hash_salt is required to hash field '%s'; set it through REDACTION_HASH_SALT", rule.Field)
+		}
+		rules[strings.ToLower(rule.Field)] = action
+	}
+	return &Redactor{
+		rules:        rules,
+		hashSalt:     cfg.HashSalt,
+		cardNoFormat: cardNoFormat,
+	}, nil
+}
+
+// Action returns the redaction action configured for a field, or "" if the field is not redacted.
+func (r *Redactor) Action(field string) string {
+	if r == nil {
+		return ""
+	}
+	return r.rules[strings.ToLower(field)]
+}
+
+// Apply redacts a single field value according to the configured action.
+func (r *Redactor) Apply(action, value string) string {
+	switch action {
+	case ActionMask:
+		if len(value) <= maskVisibleChars {
+			return strings.Repeat("*", len(value))
+		}
+		return strings.Repeat("*", len(value)-maskVisibleChars) + value[len(value)-maskVisibleChars:]
+	case ActionHash:
+		sum := sha256.Sum256([]byte(r.hashSalt + value))
+		return "sha256:" + hex.EncodeToString(sum[:])[:hashLength]
+	case ActionDrop:
+		return ""
+	case ActionCard:
+		return util.MaskCardNo(value, r.cardNoFormat)
+	default:
+		return value
+	}
+}
+
+// Value returns a redacted, JSON-compatible copy of v.
+func (r *Redactor) Value(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return unloggable(v)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return string(data)
+	}
+	return r.walk(generic)
+}
+
+// String returns v as redacted JSON text suitable for log messages.
+func (r *Redactor) String(v interface{}) string {
+	data, err := json.Marshal(r.Value(v))
+	if err != nil {
+		return unloggable(v)
+	}
+	return string(data)
+}
+
+// Body returns a raw HTTP body as redacted text, treating it as JSON when possible.
+func (r *Redactor) Body(data []byte) string {
+	if json.Valid(data) {
+		return r.String(json.RawMessage(data))
+	}
+	return string(data)
+}
+
+// unloggable stands in for a value that cannot be redacted, so that it is never logged as is.
+func unloggable(v interface{}) string {
+	return fmt.Sprintf("<unloggable %T>", v)
+}
+
+func (r *Redactor) walk(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			action := r.Action(key)
+			switch action {
+			case "":
+				val[key] = r.walk(child)
+			case ActionDrop:
+				delete(val, key)
+			default:
+				if s, ok := child.(string); ok {
+					val[key] = r.Apply(action, s)
+				} else {
+					val[key] = r.Apply(action, fmt.Sprint(child))
+				}
+			}
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = r.walk(child)
+		}
+		return val
+	default:
+		return val
+	}
+}
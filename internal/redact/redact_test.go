@@ -0,0 +1,121 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"notification_batch/internal/config"
+	"notification_batch/internal/util"
+)
+
+func newTestRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	r, err := New(config.RedactionConfig{
+		HashSalt: "salt",
+		Rules: []config.RedactionRule{
+			{Field: "user_token", Action: "hash"},
+			{Field: "mobile", Action: "mask"},
+			{Field: "message_th", Action: "DROP"},
+			{Field: "card_no", Action: "card"},
+		},
+	}, util.CardMaskFirst6Last4)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RedactionConfig
+		wantErr bool
+	}{
+		{"valid", config.RedactionConfig{HashSalt: "salt", Rules: []config.RedactionRule{{Field: "a", Action: "hash"}}}, false},
+		{"unknown action", config.RedactionConfig{Rules: []config.RedactionRule{{Field: "a", Action: "encrypt"}}}, true},
+		{"hash without salt", config.RedactionConfig{Rules: []config.RedactionRule{{Field: "a", Action: "hash"}}}, true},
+		{"mask without salt", config.RedactionConfig{Rules: []config.RedactionRule{{Field: "a", Action: "mask"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg, util.CardMaskLast4); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	r := newTestRedactor(t)
+	tests := []struct {
+		name   string
+		action string
+		value  string
+		want   string
+	}{
+		{"mask", ActionMask, "0812345678", "******5678"},
+		{"mask short", ActionMask, "123", "***"},
+		{"drop", ActionDrop, "secret", ""},
+		{"card", ActionCard, "4111111111111111", "411111******1111"},
+		{"no action keeps digit runs", "", "Queued 20261019013009-3f2a9c1b", "Queued 20261019013009-3f2a9c1b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Apply(tt.action, tt.value); got != tt.want {
+				t.Errorf("Apply(%q, %q) = %q, want %q", tt.action, tt.value, got, tt.want)
+			}
+		})
+	}
+
+	hashed := r.Apply(ActionHash, "token")
+	if !strings.HasPrefix(hashed, "sha256:") || strings.Contains(hashed, "token") {
+		t.Errorf("Apply(hash) = %q, want a sha256 digest", hashed)
+	}
+	unsalted := &Redactor{}
+	if unsalted.Apply(ActionHash, "token") == hashed {
+		t.Error("Apply(hash) does not depend on the salt")
+	}
+}
+
+func TestString(t *testing.T) {
+	r := newTestRedactor(t)
+	type inner struct {
+		UserToken string `json:"user_token"`
+		Mobile    string `json:"mobile"`
+	}
+	type request struct {
+		CardNo    string  `json:"card_no"`
+		MessageTH string  `json:"message_th"`
+		Amount    int     `json:"amount"`
+		Note      string  `json:"note"`
+		Customer  inner   `json:"customer"`
+		Contacts  []inner `json:"contacts"`
+	}
+	got := r.String(request{
+		CardNo:    "4111111111111111",
+		MessageTH: "hello",
+		Amount:    100,
+		Note:      "ref 4111111111111111",
+		Customer:  inner{UserToken: "token", Mobile: "0812345678"},
+		Contacts:  []inner{{Mobile: "0899999999"}},
+	})
+
+	for _, want := range []string{`"card_no":"411111******1111"`, `"amount":100`, `"mobile":"******5678"`, `"mobile":"******9999"`, `"user_token":"sha256:`, `"note":"ref 4111111111111111"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %s, want it to contain %s", got, want)
+		}
+	}
+	for _, unwanted := range []string{"message_th", `"token"`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("String() = %s, want it not to contain %s", got, unwanted)
+		}
+	}
+}
+
+func TestStringUnmarshalable(t *testing.T) {
+	r := newTestRedactor(t)
+	got := r.String(map[string]interface{}{"user_token": "token", "ch": make(chan int)})
+	if strings.Contains(got, "token") {
+		t.Errorf("String() = %s, want the value withheld", got)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAPIKey rejects requests without an "Authorization: Bearer <key>" header matching one of keys.
+// When no keys are configured every request is rejected.
+func requireAPIKey(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			for _, key := range keys {
+				if key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		keys []string
+		auth string
+		want int
+	}{
+		{"valid key", []string{"old", "secret"}, "Bearer secret", http.StatusOK},
+		{"wrong key", []string{"secret"}, "Bearer other", http.StatusUnauthorized},
+		{"raw key without scheme", []string{"secret"}, "secret", http.StatusUnauthorized},
+		{"other scheme", []string{"secret"}, "Basic secret", http.StatusUnauthorized},
+		{"lowercase scheme", []string{"secret"}, "bearer secret", http.StatusUnauthorized},
+		{"missing header", []string{"secret"}, "", http.StatusUnauthorized},
+		{"empty bearer with empty key", []string{""}, "Bearer ", http.StatusUnauthorized},
+		{"no keys configured", nil, "Bearer secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", requireAPIKey(tt.keys), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
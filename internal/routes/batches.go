@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/scheduler"
+	"notification_batch/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runBatchRequest is the optional body of POST /batches/:name/run.
+type runBatchRequest struct {
+	FileName     string `json:"file_name"`
+	BusinessDate string `json:"business_date"` // YYYY-MM-DD
+}
+
+func setupBatchRoutes(router gin.IRouter, apiKeys []string) {
+	batches := router.Group("/batches", requireAPIKey(apiKeys))
+	batches.POST("/:name/run", runBatch)
+}
+
+// runBatch starts the send job of a batch in the background and returns the run ID to poll at GET /runs/:id.
+func runBatch(c *gin.Context) {
+	var req runBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	opts, err := req.options()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	r, err := scheduler.Trigger(c.Param("name"), opts)
+	if errors.Is(err, scheduler.ErrUnknownBatch) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, scheduler.ErrBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, scheduler.ErrStopped) || errors.Is(err, leader.ErrNotLeader) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.AppLogger.Sugar().Infow("Batch run triggered", "batch", r.Batch, "run_id", r.ID, "file_name", r.FileName, "business_date", r.BusinessDate, "client_ip", c.ClientIP())
+	c.JSON(http.StatusAccepted, gin.H{"run_id": r.ID, "status_url": "/runs/" + r.ID})
+}
+
+func (req runBatchRequest) options() (batch.RunOptions, error) {
+	var opts batch.RunOptions
+	if req.FileName != "" {
+		if filepath.Base(req.FileName) != req.FileName || req.FileName == "." || req.FileName == ".." {
+			return opts, fmt.Errorf("invalid file_name '%s'", req.FileName)
+		}
+		opts.FileName = req.FileName
+	}
+	if req.BusinessDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.BusinessDate, util.Location())
+		if err != nil {
+			return opts, fmt.Errorf("invalid business_date '%s', expected YYYY-MM-DD", req.BusinessDate)
+		}
+		opts.BusinessDate = date
+	}
+	return opts, nil
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"notification_batch/internal/logger"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dlqHandler serves the dead-letter queue endpoints.
+type dlqHandler struct {
+	store *queue.Store
+}
+
+func setupDLQRoutes(router gin.IRouter, apiKeys []string, store *queue.Store) {
+	h := &dlqHandler{store: store}
+	dlq := router.Group("/dlq", requireAPIKey(apiKeys))
+	dlq.GET("", h.list)
+	dlq.POST("/replay", h.replayAll)
+	dlq.GET("/:id", h.get)
+	dlq.POST("/:id/replay", h.replay)
+}
+
+// list returns dead-lettered notifications, filtered by the batch, run_id, source_file and date (YYYY-MM-DD) query parameters.
+func (h *dlqHandler) list(c *gin.Context) {
+	filter, err := dlqFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	notifications, err := h.store.List(queue.StateDeadLetter, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if notifications == nil {
+		notifications = []*queue.Notification{}
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(notifications), "items": notifications})
+}
+
+func (h *dlqHandler) get(c *gin.Context) {
+	n, err := h.store.Get(queue.StateDeadLetter, c.Param("id"))
+	if errors.Is(err, queue.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, n)
+}
+
+func (h *dlqHandler) replay(c *gin.Context) {
+	n, err := h.store.Replay(c.Param("id"))
+	if errors.Is(err, queue.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.AppLogger.Sugar().Infow("Dead-lettered notification replayed", "id", n.ID, "batch", n.Batch, "run_id", n.RunID, "correlation_id", n.CorrelationID)
+	c.JSON(http.StatusAccepted, gin.H{"replayed": []string{n.ID}})
+}
+
+// replayAll replays every dead-lettered notification matching the filter; batch or run_id is required.
+func (h *dlqHandler) replayAll(c *gin.Context) {
+	filter, err := dlqFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Batch == "" && filter.RunID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch or run_id query parameter is required"})
+		return
+	}
+
+	notifications, err := h.store.List(queue.StateDeadLetter, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	replayed := []string{}
+	for _, n := range notifications {
+		if _, err := h.store.Replay(n.ID); err != nil {
+			logger.AppLogger.Sugar().Errorf("Failed to replay notification '%s': %v", n.ID, err)
+			continue
+		}
+		replayed = append(replayed, n.ID)
+	}
+	logger.AppLogger.Sugar().Infow("Dead-lettered notifications replayed", "batch", filter.Batch, "run_id", filter.RunID, "count", len(replayed))
+	c.JSON(http.StatusAccepted, gin.H{"replayed": replayed})
+}
+
+func dlqFilter(c *gin.Context) (queue.Filter, error) {
+	filter := queue.Filter{
+		Batch:      c.Query("batch"),
+		RunID:      c.Query("run_id"),
+		SourceFile: c.Query("source_file"),
+	}
+	if date := c.Query("date"); date != "" {
+		d, err := time.ParseInLocation("2006-01-02", date, util.Location())
+		if err != nil {
+			return queue.Filter{}, errors.New("date must be in YYYY-MM-DD format")
+		}
+		filter.CreatedOn = d
+	}
+	return filter, nil
+}
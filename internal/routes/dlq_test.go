@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notification_batch/internal/queue"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDLQRoutesRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := queue.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	setupDLQRoutes(router, []string{"secret"}, store)
+
+	tests := []struct {
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{http.MethodGet, "/dlq", "", http.StatusUnauthorized},
+		{http.MethodGet, "/dlq/20261019000000-aa", "", http.StatusUnauthorized},
+		{http.MethodPost, "/dlq/replay", "", http.StatusUnauthorized},
+		{http.MethodPost, "/dlq/20261019000000-aa/replay", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/dlq", "Bearer secret", http.StatusOK},
+		{http.MethodPost, "/dlq/20261019000000-aa/replay", "Bearer secret", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.auth, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
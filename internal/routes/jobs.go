@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"notification_batch/internal/leader"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-co-op/gocron"
+)
+
+// jobHandler serves the scheduler management endpoints.
+type jobHandler struct {
+	sch   *gocron.Scheduler
+	store *runs.Store
+}
+
+// jobStatus is a job with its next scheduled run and its most recent run.
+type jobStatus struct {
+	Name          string         `json:"name"`
+	Batch         string         `json:"batch"`
+	Job           string         `json:"job"`
+	Schedule      scheduler.Spec `json:"schedule"`
+	WeekdaysOnly  bool           `json:"weekdays_only"`
+	HolidayPolicy string         `json:"holiday_policy"`
+	OverlapPolicy string         `json:"overlap_policy"`
+	MisfirePolicy string         `json:"misfire_policy"`
+	DependsOn     string         `json:"depends_on,omitempty"`
+	Watched       bool           `json:"watched"`
+	Paused        bool           `json:"paused"`
+	NextRun       *time.Time     `json:"next_run,omitempty"`
+	LastRun       *runs.Run      `json:"last_run,omitempty"`
+}
+
+func setupJobRoutes(router gin.IRouter, apiKeys []string, sch *gocron.Scheduler, store *runs.Store) {
+	h := &jobHandler{sch: sch, store: store}
+	manage := router.Group("/jobs", requireAPIKey(apiKeys))
+	manage.GET("", h.list)
+	manage.POST("/:name/pause", h.pause)
+	manage.POST("/:name/resume", h.resume)
+	manage.PUT("/:name/schedule", h.reschedule)
+}
+
+func (h *jobHandler) list(c *gin.Context) {
+	infos := scheduler.Jobs()
+	statuses := make([]jobStatus, 0, len(infos))
+	for _, info := range infos {
+		status, err := h.status(info)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		statuses = append(statuses, status)
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(statuses), "items": statuses})
+}
+
+func (h *jobHandler) pause(c *gin.Context) {
+	h.respond(c, func() (scheduler.JobInfo, error) { return scheduler.Pause(c.Param("name")) })
+}
+
+func (h *jobHandler) resume(c *gin.Context) {
+	h.respond(c, func() (scheduler.JobInfo, error) { return scheduler.Resume(c.Param("name")) })
+}
+
+func (h *jobHandler) reschedule(c *gin.Context) {
+	// The body is a schedule: {"cron": "0 8 * * 1-5"} or {"times": ["08:00", "14:00"]}.
+	var spec scheduler.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respond(c, func() (scheduler.JobInfo, error) { return scheduler.Reschedule(c.Param("name"), spec) })
+}
+
+// respond applies a job change and returns the job's resulting status.
+func (h *jobHandler) respond(c *gin.Context, change func() (scheduler.JobInfo, error)) {
+	info, err := change()
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, leader.ErrNotLeader) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.status(info)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *jobHandler) status(info scheduler.JobInfo) (jobStatus, error) {
+	status := jobStatus{
+		Name:          info.Name,
+		Batch:         info.Batch,
+		Job:           info.Job,
+		Schedule:      info.Spec,
+		WeekdaysOnly:  info.WeekdaysOnly,
+		HolidayPolicy: info.HolidayPolicy,
+		OverlapPolicy: info.OverlapPolicy,
+		MisfirePolicy: info.MisfirePolicy,
+		DependsOn:     info.DependsOn,
+		Watched:       info.Watched,
+		Paused:        info.Paused,
+	}
+	if h.sch != nil {
+		// A job with several run times has one gocron job per time; report the earliest.
+		for _, j := range h.sch.Jobs() {
+			next := j.NextRun()
+			if hasTag(j, info.Name) && !next.IsZero() && (status.NextRun == nil || next.Before(*status.NextRun)) {
+				status.NextRun = &next
+			}
+		}
+	}
+	last, err := h.store.Last(runs.Filter{Batch: info.Batch, Job: info.Job})
+	if err != nil {
+		return status, err
+	}
+	status.LastRun = last
+	return status, nil
+}
+
+func hasTag(j *gocron.Job, tag string) bool {
+	for _, t := range j.Tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notification_batch/internal/runs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJobsRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := runs.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	setupJobRoutes(router, []string{"secret"}, nil, store)
+
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run("GET /jobs "+tt.auth, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -4,18 +4,42 @@ import (
 	"net/http"
 
 	"notification_batch/internal/config"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
+	"notification_batch/internal/queue"
+	"notification_batch/internal/runs"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-co-op/gocron"
 )
 
 // Init initializes the Gin router and sets up routes.
-func Init(router *gin.Engine, sch *gocron.Scheduler, cfgMap map[string]*config.Config) {
+func Init(router *gin.Engine, sch *gocron.Scheduler, cfgMap map[string]*config.Config) error {
 	setupRoutes(router)
+
+	outbox, err := queue.Open(queue.OutboxPath(cfgMap["default"].DataPath))
+	if err != nil {
+		return err
+	}
+	setupDLQRoutes(router, cfgMap["default"].OpsAPI.APIKeys, outbox)
+
+	runStore, err := runs.Open(runs.RunsPath(cfgMap["default"].DataPath))
+	if err != nil {
+		return err
+	}
+	if len(cfgMap["default"].OpsAPI.APIKeys) == 0 {
+		logger.AppLogger.Warn("No ops_api.api_keys configured; batch, job, dead-letter queue and run record endpoints will reject all requests.")
+	}
+	setupBatchRoutes(router, cfgMap["default"].OpsAPI.APIKeys)
+	setupRunRoutes(router, cfgMap["default"].OpsAPI.APIKeys, runStore)
+	setupJobRoutes(router, cfgMap["default"].OpsAPI.APIKeys, sch, runStore)
+	return nil
 }
 
 func setupRoutes(router *gin.Engine) {
 	router.GET("/ping", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "connected!"})
+		c.JSON(http.StatusOK, gin.H{"message": "connected!", "leader": leader.IsLeader()})
 	})
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 }
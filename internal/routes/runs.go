@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"notification_batch/internal/runs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runHandler serves the batch run history endpoints.
+type runHandler struct {
+	store *runs.Store
+}
+
+func setupRunRoutes(router gin.IRouter, apiKeys []string, store *runs.Store) {
+	h := &runHandler{store: store}
+	group := router.Group("/runs")
+	group.GET("", h.list)
+	group.GET("/:id", h.get)
+	// Records carry user tokens and notification IDs.
+	group.GET("/:id/records", requireAPIKey(apiKeys), h.records)
+}
+
+// list returns runs, newest first, filtered by the batch, date (business date, YYYY-MM-DD) and state query parameters.
+func (h *runHandler) list(c *gin.Context) {
+	filter := runs.Filter{Batch: c.Query("batch"), State: c.Query("state")}
+	if date := c.Query("date"); date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid date '%s', expected YYYY-MM-DD", date)})
+			return
+		}
+		filter.BusinessDate = date
+	}
+	list, err := h.store.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []*runs.Run{}
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(list), "items": list})
+}
+
+func (h *runHandler) get(c *gin.Context) {
+	r, err := h.store.Get(c.Param("id"))
+	if errors.Is(err, runs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// records returns the per-record outcomes of a run, optionally filtered by the outcome query parameter.
+func (h *runHandler) records(c *gin.Context) {
+	records, err := h.store.Records(c.Param("id"), c.Query("outcome"))
+	if errors.Is(err, runs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(records), "items": records})
+}
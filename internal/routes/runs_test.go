@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"notification_batch/internal/runs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunRecordsRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := runs.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&runs.Run{ID: "run-1", Job: "send", BusinessDate: "2026-10-19", State: runs.StateSucceeded, StartedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	setupRunRoutes(router, []string{"secret"}, store)
+
+	tests := []struct {
+		path string
+		auth string
+		want int
+	}{
+		{"/runs", "", http.StatusOK},
+		{"/runs/run-1", "", http.StatusOK},
+		{"/runs/run-1/records", "", http.StatusUnauthorized},
+		{"/runs/run-1/records", "Bearer wrong", http.StatusUnauthorized},
+		{"/runs/run-1/records", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.auth, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
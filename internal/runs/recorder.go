@@ -0,0 +1,140 @@
+package runs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
+)
+
+type contextKey int
+
+const recorderKey contextKey = iota
+
+// saveInterval is the longest a run's counts may lag behind its records in the store;
+// records themselves are appended as they happen.
+const saveInterval = 5 * time.Second
+
+// Recorder updates a running run with its files, record outcomes and counts as the batch progresses.
+// File changes are saved immediately and counts at most every saveInterval; the caller saves the
+// run when it finishes. A nil Recorder ignores every call, so batches can run without one.
+type Recorder struct {
+	store   *Store
+	run     *Run
+	mu      sync.Mutex
+	savedAt time.Time
+}
+
+// NewRecorder returns a recorder that persists progress of r to s.
+func NewRecorder(s *Store, r *Run) *Recorder {
+	return &Recorder{store: s, run: r}
+}
+
+// WithRecorder returns a copy of ctx carrying rec.
+func WithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey, rec)
+}
+
+// FromContext returns the recorder carried by ctx, or nil.
+func FromContext(ctx context.Context) *Recorder {
+	rec, _ := ctx.Value(recorderKey).(*Recorder)
+	return rec
+}
+
+// FileStarted marks name as being processed.
+func (rec *Recorder) FileStarted(name string) {
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.file(name) == nil {
+		rec.run.Files = append(rec.run.Files, &FileResult{Name: name, State: FileProcessing})
+	}
+	rec.save()
+}
+
+// FileFinished marks name as done, or failed if err is not nil.
+func (rec *Recorder) FileFinished(name string, err error) {
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	f := rec.file(name)
+	if f == nil {
+		f = &FileResult{Name: name}
+		rec.run.Files = append(rec.run.Files, f)
+	}
+	f.State = FileDone
+	if err != nil {
+		f.State = FileFailed
+		f.Error = err.Error()
+	}
+	rec.save()
+}
+
+// Record stores the outcome of a single record and counts it against its file.
+func (rec *Recorder) Record(r Record) {
+	if rec == nil {
+		return
+	}
+	if r.At.IsZero() {
+		r.At = time.Now()
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err := rec.store.appendRecord(rec.run, r); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record outcome for run '%s': %v", rec.run.ID, err)
+	}
+	if f := rec.file(r.File); f != nil {
+		f.Records++
+	}
+	rec.count(r.Outcome, 1)
+	metrics.AddRecords(rec.run.Batch, rec.run.Job, r.Outcome, 1)
+	rec.saveEvery()
+}
+
+// Add adds n to the count of outcome without storing individual records. Unlike Record it is
+// not counted in the records metric, as the same notifications are reported by every result run.
+func (rec *Recorder) Add(outcome string, n int) {
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.count(outcome, n)
+	rec.saveEvery()
+}
+
+func (rec *Recorder) count(outcome string, n int) {
+	if rec.run.Counts == nil {
+		rec.run.Counts = make(map[string]int)
+	}
+	rec.run.Counts[outcome] += n
+}
+
+func (rec *Recorder) file(name string) *FileResult {
+	for _, f := range rec.run.Files {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// saveEvery saves the run if it has not been saved within saveInterval.
+func (rec *Recorder) saveEvery() {
+	if time.Since(rec.savedAt) >= saveInterval {
+		rec.save()
+	}
+}
+
+func (rec *Recorder) save() {
+	rec.savedAt = time.Now()
+	if err := rec.store.Save(rec.run); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to save run '%s': %v", rec.run.ID, err)
+	}
+}
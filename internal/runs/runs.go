@@ -0,0 +1,408 @@
+package runs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"notification_batch/internal/util"
+)
+
+const (
+	fileExt    = ".json"
+	recordsExt = ".records.jsonl"
+)
+
+// Run states.
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	// StateRejected marks a run that never started, e.g. refused while another was in progress.
+	StateRejected = "rejected"
+)
+
+// File states.
+const (
+	FileProcessing = "processing"
+	FileDone       = "done"
+	FileFailed     = "failed"
+)
+
+// Record outcomes.
+const (
+	OutcomeQueued       = "queued"
+	OutcomeDeferred     = "deferred"
+	OutcomeNotTriggered = "not_triggered"
+	OutcomeFailed       = "failed"
+	OutcomeSkipped      = "skipped"
+	OutcomeSent         = "sent"
+	OutcomePending      = "pending"
+)
+
+// Run triggers.
+const (
+	TriggerSchedule     = "schedule"
+	TriggerHolidayShift = "holiday_shift"
+	TriggerAPI          = "api"
+	TriggerCatchUp      = "catch_up"
+	TriggerWatch        = "watch"
+)
+
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+// ErrNotFound is returned when a run does not exist.
+var ErrNotFound = errors.New("run not found")
+
+// Run records a single execution of a batch job.
+type Run struct {
+	ID           string         `json:"id"`
+	Batch        string         `json:"batch"`
+	Job          string         `json:"job"`
+	Trigger      string         `json:"trigger"`
+	FileName     string         `json:"file_name,omitempty"`
+	BusinessDate string         `json:"business_date"`
+	State        string         `json:"state"`
+	StartedAt    time.Time      `json:"started_at"`
+	EndedAt      *time.Time     `json:"ended_at,omitempty"`
+	Files        []*FileResult  `json:"files"`
+	Counts       map[string]int `json:"counts"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// FileResult records the processing of one file within a run.
+type FileResult struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Record is the outcome of a single input record within a run.
+type Record struct {
+	File           string    `json:"file"`
+	Line           int       `json:"line"`
+	Outcome        string    `json:"outcome"`
+	Detail         string    `json:"detail,omitempty"`
+	CorrelationID  string    `json:"correlation_id,omitempty"`
+	NotificationID string    `json:"notification_id,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// Filter selects runs when listing; zero fields match everything.
+type Filter struct {
+	Batch        string
+	Job          string
+	BusinessDate string // YYYY-MM-DD
+	State        string
+}
+
+func (f Filter) matches(r *Run) bool {
+	return (f.Batch == "" || r.Batch == f.Batch) &&
+		(f.Job == "" || r.Job == f.Job) &&
+		(f.BusinessDate == "" || r.BusinessDate == f.BusinessDate) &&
+		(f.State == "" || r.State == f.State)
+}
+
+// Finish marks r as ended, failed if err is not nil.
+func (r *Run) Finish(err error) {
+	now := time.Now()
+	r.EndedAt = &now
+	r.State = StateSucceeded
+	if err != nil {
+		r.State = StateFailed
+		r.Error = err.Error()
+	}
+}
+
+// Reject marks r as ended without having started because of cause.
+func (r *Run) Reject(cause error) {
+	now := time.Now()
+	r.EndedAt = &now
+	r.State = StateRejected
+	r.Error = cause.Error()
+}
+
+// Store is a directory-backed run history, one JSON file per run in a directory per business date,
+// so runs of a date are read without the rest of the history and old dates are pruned as a whole.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// dateLayout names the business date directories.
+const dateLayout = "2006-01-02"
+
+// RunsPath returns the directory of the run history under dataPath.
+func RunsPath(dataPath string) string {
+	return filepath.Join(dataPath, "runs")
+}
+
+// Open opens the store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run directory '%s': %v", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save persists r atomically by writing a temporary file and renaming it.
+func (s *Store) Save(r *Run) error {
+	if !idPattern.MatchString(r.ID) {
+		return fmt.Errorf("invalid run ID '%s'", r.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run '%s': %v", r.ID, err)
+	}
+
+	dir := s.dateDir(r.BusinessDate)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory '%s': %v", dir, err)
+	}
+	path := filepath.Join(dir, r.ID+fileExt)
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run '%s': %v", r.ID, err)
+	}
+	return nil
+}
+
+// Get returns the run with the given ID.
+func (s *Store) Get(id string) (*Run, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return readRun(path)
+}
+
+// List returns the runs matching filter, newest first.
+// A filter with a business date only reads the runs of that date.
+func (s *Store) List(filter Filter) ([]*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dates := []string{filter.BusinessDate}
+	if filter.BusinessDate == "" {
+		var err error
+		if dates, err = s.dates(); err != nil {
+			return nil, err
+		}
+	}
+	var matched []*Run
+	for _, date := range dates {
+		runs, err := s.listDate(date, filter)
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, runs...)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].StartedAt.After(matched[j].StartedAt) })
+	return matched, nil
+}
+
+// Last returns the most recent run matching filter of the latest business date that has one, or
+// nil if there is none. Dates are read newest first, so usually only one date is read.
+func (s *Store) Last(filter Filter) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dates, err := s.dates()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(dates) - 1; i >= 0; i-- {
+		matched, err := s.listDate(dates[i], filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) > 0 {
+			sort.Slice(matched, func(i, j int) bool { return matched[i].StartedAt.After(matched[j].StartedAt) })
+			return matched[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// listDate returns the runs of date matching filter; s.mu must be held.
+func (s *Store) listDate(date string, filter Filter) ([]*Run, error) {
+	dir := s.dateDir(date)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run directory '%s': %v", dir, err)
+	}
+	var matched []*Run
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		r, err := readRun(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if filter.matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched, nil
+}
+
+// Prune removes the runs and records of business dates before cutoff and returns how many dates were removed.
+func (s *Store) Prune(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dates, err := s.dates()
+	if err != nil {
+		return 0, err
+	}
+	limit := cutoff.Format(dateLayout)
+	removed := 0
+	for _, date := range dates {
+		if date >= limit {
+			continue
+		}
+		if err := os.RemoveAll(s.dateDir(date)); err != nil {
+			return removed, fmt.Errorf("failed to remove runs of %s: %v", date, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Records returns the records of the run with the given ID, optionally only those with outcome.
+func (s *Store) Records(id, outcome string) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(recordsPath(path))
+	if os.IsNotExist(err) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open records of run '%s': %v", id, err)
+	}
+	defer file.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal record of run '%s': %v", id, err)
+		}
+		if outcome == "" || rec.Outcome == outcome {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records of run '%s': %v", id, err)
+	}
+	return records, nil
+}
+
+func (s *Store) appendRecord(r *Run, rec Record) error {
+	id := r.ID
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record of run '%s': %v", id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.dateDir(r.BusinessDate)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory '%s': %v", dir, err)
+	}
+	file, err := os.OpenFile(recordsPath(filepath.Join(dir, id+fileExt)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open records of run '%s': %v", id, err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write record of run '%s': %v", id, err)
+	}
+	return nil
+}
+
+// dateDir returns the directory holding the runs of a business date.
+func (s *Store) dateDir(date string) string {
+	if date == "" {
+		date = "undated"
+	}
+	return filepath.Join(s.dir, date)
+}
+
+// dates returns the business dates that have runs.
+func (s *Store) dates() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run directory '%s': %v", s.dir, err)
+	}
+	var dates []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dates = append(dates, entry.Name())
+		}
+	}
+	return dates, nil
+}
+
+// find returns the path of the run with the given ID.
+func (s *Store) find(id string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*", id+fileExt))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up run '%s': %v", id, err)
+	}
+	if len(matches) == 0 {
+		return "", ErrNotFound
+	}
+	return matches[0], nil
+}
+
+// recordsPath returns the records file of the run stored at path.
+func recordsPath(path string) string {
+	return strings.TrimSuffix(path, fileExt) + recordsExt
+}
+
+func readRun(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run '%s': %v", filepath.Base(path), err)
+	}
+	r := &Run{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run '%s': %v", filepath.Base(path), err)
+	}
+	return r, nil
+}
@@ -0,0 +1,214 @@
+package runs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"notification_batch/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func newTestRun(id, job, date, state string, started time.Time) *Run {
+	return &Run{ID: id, Batch: "spending_alert", Job: job, BusinessDate: date, State: state, StartedAt: started}
+}
+
+func TestStoreList(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	for _, r := range []*Run{
+		newTestRun("send-1", "send", "2026-10-18", StateSucceeded, start.AddDate(0, 0, -1)),
+		newTestRun("send-2", "send", "2026-10-19", StateFailed, start),
+		newTestRun("send-3", "send", "2026-10-19", StateSucceeded, start.Add(time.Hour)),
+		newTestRun("result-1", "result", "2026-10-19", StateRunning, start.Add(2*time.Hour)),
+	} {
+		if err := s.Save(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all, newest first", Filter{}, []string{"result-1", "send-3", "send-2", "send-1"}},
+		{"business date", Filter{BusinessDate: "2026-10-19"}, []string{"result-1", "send-3", "send-2"}},
+		{"date, job and state", Filter{Job: "send", BusinessDate: "2026-10-19", State: StateSucceeded}, []string{"send-3"}},
+		{"date without runs", Filter{BusinessDate: "2026-10-20"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := s.List(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range list {
+				got = append(got, r.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("List() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("List() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if r, err := s.Get("send-2"); err != nil || r.State != StateFailed {
+		t.Errorf("Get() = %+v, %v", r, err)
+	}
+	if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing run error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get("../send-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with a path error = %v, want ErrNotFound", err)
+	}
+
+	for filter, want := range map[Filter]string{
+		{Job: "send"}:                        "send-3",
+		{Job: "send", State: StateFailed}:    "send-2",
+		{Job: "send", State: StateRunning}:   "",
+		{Batch: "encb"}:                      "",
+		{Job: "result", State: StateRunning}: "result-1",
+	} {
+		r, err := s.Last(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if r != nil {
+			got = r.ID
+		}
+		if got != want {
+			t.Errorf("Last(%+v) = %q, want %q", filter, got, want)
+		}
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, date := range []string{"2026-07-20", "2026-07-21", "2026-10-19"} {
+		r := newTestRun("run-"+date, "send", date, StateSucceeded, time.Now())
+		if err := s.Save(r); err != nil {
+			t.Fatal(err)
+		}
+		NewRecorder(s, r).Record(Record{File: "sa.txt", Line: 1, Outcome: OutcomeQueued})
+	}
+
+	removed, err := s.Prune(time.Date(2026, 7, 21, 12, 0, 0, 0, time.UTC))
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune() = %d, %v, want 1 date removed", removed, err)
+	}
+	if _, err := s.Get("run-2026-07-20"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pruned run still found: %v", err)
+	}
+	if _, err := s.Records("run-2026-07-20", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pruned records still found: %v", err)
+	}
+	for _, id := range []string{"run-2026-07-21", "run-2026-10-19"} {
+		if records, err := s.Records(id, ""); err != nil || len(records) != 1 {
+			t.Errorf("Records(%s) = %v, %v, want the kept record", id, records, err)
+		}
+	}
+}
+
+func TestRecorderBatchesSaves(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRun("run-1", "send", "2026-10-19", StateRunning, time.Now())
+	if err := s.Save(r); err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder(s, r)
+	rec.FileStarted("sa.txt")
+
+	const n = 1000
+	for i := 1; i <= n; i++ {
+		rec.Record(Record{File: "sa.txt", Line: i, Outcome: OutcomeQueued})
+	}
+
+	saved, err := s.Get("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Counts[OutcomeQueued] == n {
+		t.Errorf("run saved after every record, want saves batched")
+	}
+	if records, err := s.Records("run-1", OutcomeQueued); err != nil || len(records) != n {
+		t.Errorf("Records() = %d, %v, want all %d records appended", len(records), err, n)
+	}
+
+	rec.FileFinished("sa.txt", nil)
+	saved, err = s.Get("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Counts[OutcomeQueued] != n || saved.Files[0].Records != n || saved.Files[0].State != FileDone {
+		t.Errorf("run after FileFinished = counts %v, files %+v", saved.Counts, saved.Files[0])
+	}
+}
+
+// recordsMetric returns records_total for a batch, job and outcome.
+func recordsMetric(t *testing.T, batch, job, outcome string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "notification_batch_records_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["batch"] == batch && labels["job"] == job && labels["outcome"] == outcome {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestRecorderMetrics(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := &Run{ID: "send-1", Batch: "metric_test", Job: "send", BusinessDate: "2026-10-19", State: StateRunning, StartedAt: time.Now()}
+	result := &Run{ID: "result-1", Batch: "metric_test", Job: "result", BusinessDate: "2026-10-19", State: StateRunning, StartedAt: time.Now()}
+
+	NewRecorder(s, send).Record(Record{File: "sa.txt", Line: 1, Outcome: OutcomeQueued})
+	// Every result run reports all notifications of the day again.
+	for i := 0; i < 3; i++ {
+		NewRecorder(s, result).Add(OutcomeSent, 10)
+	}
+
+	if got := recordsMetric(t, "metric_test", "send", OutcomeQueued); got != 1 {
+		t.Errorf("records_total for send = %v, want 1", got)
+	}
+	if got := recordsMetric(t, "metric_test", "result", OutcomeSent); got != 0 {
+		t.Errorf("records_total for result = %v, want 0", got)
+	}
+	if result.Counts[OutcomeSent] != 30 {
+		t.Errorf("result run counts = %v", result.Counts)
+	}
+}
@@ -0,0 +1,240 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/calendar"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Misfire policies applied on startup to scheduled runs missed while the application was down.
+const (
+	MisfireRunOnce = "run_once"
+	MisfireRunAll  = "run_all"
+	MisfireSkip    = "skip"
+)
+
+// maxMissedRuns bounds how many missed runs of a job are caught up.
+const maxMissedRuns = 31
+
+var (
+	// successMu guards lastSuccess, the time of the last successful scheduled run of each job.
+	successMu       sync.Mutex
+	lastSuccess     map[string]time.Time
+	lastSuccessFile string
+)
+
+// lastSuccessPath returns the file holding the last successful run of each job under dataPath.
+func lastSuccessPath(dataPath string) string {
+	return filepath.Join(dataPath, "scheduler", "last_success.json")
+}
+
+func loadLastSuccess(path string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return times, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last successful runs '%s': %v", path, err)
+	}
+	if err := json.Unmarshal(data, &times); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last successful runs '%s': %v", path, err)
+	}
+	return times, nil
+}
+
+// recordSuccess records t as the time up to which scheduled runs of j are no longer caught up;
+// successMu must not be held.
+func recordSuccess(j *job, t time.Time) {
+	successMu.Lock()
+	defer successMu.Unlock()
+	lastSuccess[j.tag()] = t
+	if err := saveLastSuccess(lastSuccessFile, lastSuccess); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record last successful run of %s: %v", j.label, err)
+	}
+}
+
+// reloadLastSuccess replaces the last successful runs with those recorded in lastSuccessFile.
+func reloadLastSuccess() {
+	times, err := loadLastSuccess(lastSuccessFile)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to reload last successful runs, keeping those loaded at startup: %v", err)
+		return
+	}
+	successMu.Lock()
+	lastSuccess = times
+	successMu.Unlock()
+}
+
+// reportLastSuccess sets the last success gauge of each job from the recorded last successful
+// runs, so that it survives a restart.
+func reportLastSuccess() {
+	mu.Lock()
+	defer mu.Unlock()
+	successMu.Lock()
+	defer successMu.Unlock()
+	for _, j := range jobs {
+		if t, ok := lastSuccess[j.tag()]; ok {
+			metrics.SetJobLastSuccess(j.batch, j.name, t)
+		}
+	}
+}
+
+// saveLastSuccess writes the last successful runs atomically.
+func saveLastSuccess(path string, times map[string]time.Time) error {
+	data, err := json.MarshalIndent(times, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal last successful runs: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for last successful runs '%s': %v", path, err)
+	}
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write last successful runs '%s': %v", path, err)
+	}
+	return nil
+}
+
+// validMisfirePolicy reports whether policy is a known misfire policy.
+func validMisfirePolicy(policy string) bool {
+	return policy == MisfireRunOnce || policy == MisfireRunAll || policy == MisfireSkip
+}
+
+// catchUp runs the scheduled runs each job missed since its last successful run, according to
+// its misfire policy, once this replica is the leader. The caller registers it with running.begin.
+func catchUp() {
+	defer running.end()
+	select {
+	case <-leader.Elected():
+	case <-ctx.Done():
+		return
+	}
+	until := util.Now()
+	// The previous leader kept recording successes and may have changed jobs while this replica was on standby.
+	reloadLastSuccess()
+	reloadOverrides()
+	restoreShifted()
+
+	mu.Lock()
+	pending := append([]*job(nil), jobs...)
+	mu.Unlock()
+	for _, j := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		catchUpJob(j, until)
+	}
+	armShifted()
+}
+
+func catchUpJob(j *job, until time.Time) {
+	successMu.Lock()
+	since, ok := lastSuccess[j.tag()]
+	successMu.Unlock()
+	if !ok {
+		// Nothing is known about earlier runs; count missed runs from now on.
+		recordSuccess(j, until)
+		return
+	}
+	mu.Lock()
+	paused := j.paused
+	mu.Unlock()
+	if paused || j.watched {
+		return
+	}
+
+	missed, err := j.missedRuns(since, until)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to look for missed runs of %s: %v", j.label, err)
+		return
+	}
+	if len(missed) == 0 {
+		return
+	}
+	switch j.misfirePolicy {
+	case MisfireSkip:
+		logger.AppLogger.Sugar().Warnf("%s missed %d scheduled runs since %s, the first at %s; trigger them manually if needed.",
+			j.label, len(missed), since.Format(time.RFC3339), missed[0].Format(time.RFC3339))
+		recordSuccess(j, until)
+		return
+	case MisfireRunOnce:
+		missed = missed[len(missed)-1:]
+	}
+	for _, at := range missed {
+		if ctx.Err() != nil {
+			return
+		}
+		if name, ok := j.calendar.Holiday(at); ok {
+			// Runs on the next business day, or at once if that has passed too.
+			shift(j, at, name)
+			continue
+		}
+		logger.AppLogger.Sugar().Infof("Catching up %s missed at %s.", j.label, at.Format(time.RFC3339))
+		opts := batch.RunOptions{BusinessDate: at}
+		if j.name == JobSend {
+			// A send run processes the files on the server now, so its results belong to today.
+			opts = batch.RunOptions{}
+		}
+		runScheduled(j, runs.TriggerCatchUp, opts)
+	}
+}
+
+// missedRuns returns the scheduled times of j after since and up to until, oldest first,
+// leaving out weekends and holidays on which the job would not have run.
+func (j *job) missedRuns(since, until time.Time) ([]time.Time, error) {
+	mu.Lock()
+	spec := j.spec
+	mu.Unlock()
+	exprs, withSeconds, err := spec.crons(j.weekdaysOnly)
+	if err != nil {
+		return nil, err
+	}
+	fields := cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor
+	if withSeconds {
+		fields |= cron.Second
+	}
+	parser := cron.NewParser(fields)
+
+	var missed []time.Time
+	for _, expr := range exprs {
+		schedule, err := parser.Parse("CRON_TZ=" + util.Location().String() + " " + expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %v", expr, err)
+		}
+		for t := schedule.Next(since); !t.IsZero() && !t.After(until); t = schedule.Next(t) {
+			if j.wouldRun(t) {
+				missed = append(missed, t)
+			}
+		}
+	}
+	sort.Slice(missed, func(a, b int) bool { return missed[a].Before(missed[b]) })
+	if len(missed) > maxMissedRuns {
+		missed = missed[len(missed)-maxMissedRuns:]
+	}
+	return missed, nil
+}
+
+// wouldRun reports whether j runs when scheduled at t; runs shifted off a holiday still count.
+func (j *job) wouldRun(t time.Time) bool {
+	if j.weekdaysOnly && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	if _, ok := j.calendar.Holiday(t); ok && j.holidayPolicy != calendar.PolicyShift {
+		return false
+	}
+	return true
+}
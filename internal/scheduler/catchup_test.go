@@ -0,0 +1,341 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/calendar"
+	"notification_batch/internal/config"
+	"notification_batch/internal/lock"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ranDates collects the business dates a test job ran for.
+type ranDates struct {
+	mu    sync.Mutex
+	dates []time.Time
+}
+
+func (r *ranDates) run(_ context.Context, _ *config.Config, opts batch.RunOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dates = append(r.dates, opts.BusinessDate)
+	return nil
+}
+
+func (r *ranDates) get() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.dates...)
+}
+
+// useCatchUp prepares the scheduler state a catch-up of j needs and returns what j runs.
+func useCatchUp(t *testing.T, j *job, last map[string]time.Time) *ranDates {
+	t.Helper()
+	useJobs(t, j)
+	useShifted(t)
+	dir := t.TempDir()
+	store, err := runs.Open(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStore, prevCtx, prevCancel, prevSuccess, prevSuccessFile := runStore, ctx, cancel, lastSuccess, lastSuccessFile
+	runStore = store
+	ctx, cancel = context.WithCancel(context.Background())
+	lastSuccessFile = filepath.Join(dir, "last_success.json")
+	lastSuccess = last
+	t.Cleanup(func() {
+		cancel()
+		runStore, ctx, cancel, lastSuccess, lastSuccessFile = prevStore, prevCtx, prevCancel, prevSuccess, prevSuccessFile
+	})
+
+	ran := &ranDates{}
+	j.run = ran.run
+	j.lock = lock.New(filepath.Join(dir, ".send.lock"))
+	j.overlapPolicy = OverlapQueue
+	return ran
+}
+
+func TestCatchUpJob(t *testing.T) {
+	loc := util.Location()
+	// Friday 24 October 2025 is the holiday; runs shifted off it are due on Monday.
+	holiday := time.Date(2025, 10, 24, 8, 0, 0, 0, loc)
+	since := time.Date(2025, 10, 22, 9, 0, 0, 0, loc)
+	until := time.Date(2025, 10, 28, 9, 0, 0, 0, loc)
+	thu := time.Date(2025, 10, 23, 8, 0, 0, 0, loc)
+	mon := time.Date(2025, 10, 27, 8, 0, 0, 0, loc)
+	tue := time.Date(2025, 10, 28, 8, 0, 0, 0, loc)
+
+	tests := []struct {
+		name    string
+		job     string
+		policy  string
+		noLast  bool
+		paused  bool
+		want    []time.Time
+		shifted bool
+	}{
+		{"run all", JobResult, MisfireRunAll, false, false, []time.Time{thu, mon, tue}, true},
+		{"run once", JobResult, MisfireRunOnce, false, false, []time.Time{tue}, false},
+		{"send runs once for today", JobSend, MisfireRunOnce, false, false, []time.Time{{}}, false},
+		{"skip", JobResult, MisfireSkip, false, false, nil, false},
+		{"no earlier success", JobResult, MisfireRunAll, true, false, nil, false},
+		{"paused", JobResult, MisfireRunAll, false, true, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := holidayJob(t, holiday)
+			j.name = tt.job
+			j.misfirePolicy = tt.policy
+			j.paused = tt.paused
+			last := map[string]time.Time{}
+			if !tt.noLast {
+				last[j.tag()] = since
+			}
+			ran := useCatchUp(t, j, last)
+
+			catchUpJob(j, until)
+
+			got := ran.get()
+			if tt.shifted {
+				// The holiday run is shifted to a time already past and starts on its own.
+				deadline := time.Now().Add(time.Second)
+				for len(got) < len(tt.want)+1 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+					got = ran.get()
+				}
+				for pending := 1; pending > 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+					mu.Lock()
+					pending = len(shifted)
+					mu.Unlock()
+				}
+				var others []time.Time
+				for _, d := range got {
+					if !d.Equal(holiday) {
+						others = append(others, d)
+					}
+				}
+				if len(others) != len(got)-1 {
+					t.Fatalf("ran %v, want %v and the holiday %s once", got, tt.want, holiday)
+				}
+				got = others
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ran %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("ran %v, want %v", got, tt.want)
+				}
+			}
+			successMu.Lock()
+			recorded, ok := lastSuccess[j.tag()]
+			successMu.Unlock()
+			if len(tt.want) == 0 && !tt.paused && (!ok || !recorded.Equal(until)) {
+				t.Errorf("last success = %s, %v, want %s", recorded, ok, until)
+			}
+		})
+	}
+}
+
+func TestCatchUpReloadsLastSuccess(t *testing.T) {
+	spec := Spec{Cron: "@every 1h"}
+	j := &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", configured: spec, spec: spec, misfirePolicy: MisfireRunOnce}
+	// Loaded at startup, before the previous leader recorded its last run.
+	ran := useCatchUp(t, j, map[string]time.Time{})
+	since := util.Now().Add(-90 * time.Minute)
+	if err := saveLastSuccess(lastSuccessFile, map[string]time.Time{j.tag(): since}); err != nil {
+		t.Fatal(err)
+	}
+
+	running.begin()
+	catchUp()
+
+	if got := ran.get(); len(got) != 1 {
+		t.Fatalf("ran %v, want the run missed since %s", got, since)
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	loc := util.Location()
+	// Friday 23 October 2026 is the holiday.
+	holiday := time.Date(2026, 10, 23, 8, 0, 0, 0, loc)
+	j := holidayJob(t, holiday)
+	since := time.Date(2026, 10, 21, 9, 0, 0, 0, loc)
+	until := time.Date(2026, 10, 27, 8, 0, 0, 0, loc)
+
+	tests := []struct {
+		name   string
+		spec   Spec
+		policy string
+		want   []time.Time
+	}{
+		{"holiday shifted", Spec{Times: []string{"08:00"}}, calendar.PolicyShift, []time.Time{
+			time.Date(2026, 10, 22, 8, 0, 0, 0, loc), holiday,
+			time.Date(2026, 10, 26, 8, 0, 0, 0, loc), time.Date(2026, 10, 27, 8, 0, 0, 0, loc),
+		}},
+		{"holiday skipped", Spec{Times: []string{"08:00"}}, calendar.PolicySkip, []time.Time{
+			time.Date(2026, 10, 22, 8, 0, 0, 0, loc),
+			time.Date(2026, 10, 26, 8, 0, 0, 0, loc), time.Date(2026, 10, 27, 8, 0, 0, 0, loc),
+		}},
+		{"several times a day", Spec{Times: []string{"08:00", "17:30"}}, calendar.PolicySkip, []time.Time{
+			time.Date(2026, 10, 21, 17, 30, 0, 0, loc), time.Date(2026, 10, 22, 8, 0, 0, 0, loc),
+			time.Date(2026, 10, 22, 17, 30, 0, 0, loc), time.Date(2026, 10, 26, 8, 0, 0, 0, loc),
+			time.Date(2026, 10, 26, 17, 30, 0, 0, loc), time.Date(2026, 10, 27, 8, 0, 0, 0, loc),
+		}},
+		{"cron expression", Spec{Cron: "0 12 * * 1"}, calendar.PolicySkip, []time.Time{
+			time.Date(2026, 10, 26, 12, 0, 0, 0, loc),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j.spec = tt.spec
+			j.holidayPolicy = tt.policy
+			got, err := j.missedRuns(since, until)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("missedRuns() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("missedRuns() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMissedRunsUseBusinessTimezone(t *testing.T) {
+	loc := useTimezone(t, "Asia/Bangkok")
+	j := holidayJob(t, time.Date(2026, 10, 23, 0, 0, 0, 0, loc))
+	j.spec = Spec{Times: []string{"06:00"}}
+	// 06:00 on Tuesday 20 October in Bangkok is 23:00 on Monday 19 October in UTC.
+	since := time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC)
+
+	missed, err := j.missedRuns(since, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missed) != 1 || !missed[0].Equal(time.Date(2026, 10, 20, 6, 0, 0, 0, loc)) {
+		t.Fatalf("missedRuns() = %v, want 06:00 on 2026-10-20 in %s", missed, loc)
+	}
+	opts := batch.RunOptions{BusinessDate: missed[0]}
+	if got := opts.Date().Format("2006-01-02"); got != "2026-10-20" {
+		t.Errorf("business date = %s, want 2026-10-20", got)
+	}
+}
+
+// lastSuccessMetric returns the last success gauge of a job, if it is set.
+func lastSuccessMetric(t *testing.T, batchName, jobName string) (float64, bool) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "notification_batch_job_last_success_timestamp_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["batch"] == batchName && labels["job"] == jobName {
+				return m.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestLastSuccessMetricOnlyOnRuns(t *testing.T) {
+	tests := []struct {
+		name        string
+		trigger     string
+		wantCatchUp bool
+	}{
+		{"scheduled", runs.TriggerSchedule, true},
+		{"api", runs.TriggerAPI, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{Times: []string{"08:00"}}
+			j := &job{batch: "metric_test_" + tt.name, name: JobSend, label: "Metric Test Send Batch", configured: spec, spec: spec, misfirePolicy: MisfireSkip}
+			since := util.Now().Add(-72 * time.Hour)
+			ran := useCatchUp(t, j, map[string]time.Time{})
+
+			// Seeding and skipping missed runs move the catch-up watermark only.
+			catchUpJob(j, since)
+			catchUpJob(j, util.Now())
+			if v, ok := lastSuccessMetric(t, j.batch, j.name); ok {
+				t.Fatalf("last success gauge = %v after catch-up without runs", v)
+			}
+			watermark := lastSuccess[j.tag()]
+
+			start := time.Now().Add(-time.Second)
+			if err := runScheduled(j, tt.trigger, batch.RunOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if len(ran.get()) != 1 {
+				t.Fatalf("ran %v, want one run", ran.get())
+			}
+			if v, ok := lastSuccessMetric(t, j.batch, j.name); !ok || v < float64(start.Unix()) {
+				t.Errorf("last success gauge = %v, %v after a successful run", v, ok)
+			}
+			if moved := !lastSuccess[j.tag()].Equal(watermark); moved != tt.wantCatchUp {
+				t.Errorf("catch-up watermark moved = %v, want %v", moved, tt.wantCatchUp)
+			}
+		})
+	}
+}
+
+func TestReportLastSuccess(t *testing.T) {
+	spec := Spec{Times: []string{"08:00"}}
+	j := &job{batch: "metric_restart", name: JobResult, label: "Metric Restart Result Batch", configured: spec, spec: spec}
+	// Recorded by the run before the restart.
+	last := time.Date(2026, 10, 16, 18, 0, 0, 0, util.Location())
+	useCatchUp(t, j, map[string]time.Time{j.tag(): last})
+
+	reportLastSuccess()
+	if v, ok := lastSuccessMetric(t, j.batch, j.name); !ok || v != float64(last.Unix()) {
+		t.Errorf("last success gauge = %v, %v, want %v", v, ok, last.Unix())
+	}
+}
+
+func TestSetupBatchJobsMisfirePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		send    string
+		result  string
+		wantErr bool
+	}{
+		{"run once", MisfireRunOnce, MisfireRunOnce, false},
+		{"result runs all", MisfireSkip, MisfireRunAll, false},
+		{"send runs all", MisfireRunAll, MisfireRunOnce, true},
+		{"unknown", "latest", MisfireRunOnce, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useJobs(t)
+			cfg := &config.Config{}
+			cfg.SpendingAlert.FTP.LocalPath = t.TempDir()
+			cfg.SpendingAlert.Schedule = config.ScheduleConfig{SendTime: "08:00", ResultTime: "18:00", SendMisfire: tt.send, ResultMisfire: tt.result}
+
+			err := setupBatchJobs(map[string]*config.Config{"spending_alert": cfg}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setupBatchJobs() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/runs"
+)
+
+// dependencyPollInterval is how often a waiting job checks whether its upstream job has completed.
+const dependencyPollInterval = 30 * time.Second
+
+// defaultDependencyTimeout is how long a job waits for its upstream job unless configured.
+const defaultDependencyTimeout = 2 * time.Hour
+
+// waitForUpstream blocks until the upstream job of j has run for the business date of opts.
+// It returns an error once the dependency timeout of j passes or the scheduler stops.
+func waitForUpstream(j *job, opts batch.RunOptions) error {
+	date := opts.Date().Format("2006-01-02")
+	deadline := time.NewTimer(j.dependencyTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	waiting := false
+	for {
+		done, err := upstreamDone(j.dependsOn, date)
+		if err != nil {
+			logger.AppLogger.Sugar().Errorf("Failed to check %s for %s: %v", j.dependsOn.label, date, err)
+		}
+		if done {
+			if waiting {
+				logger.AppLogger.Sugar().Infof("%s completed for %s; starting %s.", j.dependsOn.label, date, j.label)
+			}
+			return nil
+		}
+		if !waiting {
+			logger.AppLogger.Sugar().Infof("%s is waiting for %s to complete for %s.", j.label, j.dependsOn.label, date)
+			waiting = true
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return fmt.Errorf("%s did not complete for %s within %s", j.dependsOn.label, date, j.dependencyTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// upstreamDone reports whether upstream has finished a run for the business date, successful
+// or not: a failed send still leaves results to collect for the records it sent. Rejected runs
+// never started and do not count.
+func upstreamDone(upstream *job, date string) (bool, error) {
+	list, err := runStore.List(runs.Filter{Batch: upstream.batch, Job: upstream.name, BusinessDate: date})
+	if err != nil {
+		return false, err
+	}
+	for _, r := range list {
+		if r.State == runs.StateSucceeded || r.State == runs.StateFailed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
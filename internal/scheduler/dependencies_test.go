@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/config"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+func TestUpstreamDone(t *testing.T) {
+	send := &job{batch: "spending_alert", name: JobSend}
+	prevStore := runStore
+	t.Cleanup(func() { runStore = prevStore })
+
+	tests := []struct {
+		name string
+		runs []*runs.Run
+		date string
+		want bool
+	}{
+		{"no run", nil, "2026-10-19", false},
+		{"still running", []*runs.Run{{ID: "r1", State: runs.StateRunning}}, "2026-10-19", false},
+		{"succeeded", []*runs.Run{{ID: "r2", State: runs.StateSucceeded}}, "2026-10-19", true},
+		{"failed", []*runs.Run{{ID: "r3", State: runs.StateFailed}}, "2026-10-19", true},
+		{"other date", []*runs.Run{{ID: "r4", State: runs.StateSucceeded, BusinessDate: "2026-10-18"}}, "2026-10-19", false},
+		{"other job", []*runs.Run{{ID: "r5", Job: JobResult, State: runs.StateSucceeded}}, "2026-10-19", false},
+		{"rejected", []*runs.Run{{ID: "r6", State: runs.StateRejected}}, "2026-10-19", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := runs.Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runStore = store
+			for _, r := range tt.runs {
+				r.Batch = send.batch
+				if r.Job == "" {
+					r.Job = send.name
+				}
+				if r.BusinessDate == "" {
+					r.BusinessDate = tt.date
+				}
+				r.StartedAt = time.Now()
+				if err := store.Save(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := upstreamDone(send, tt.date)
+			if err != nil || got != tt.want {
+				t.Errorf("upstreamDone() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectedSendIsNotDone(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	spec := Spec{Cron: "@every 1h"}
+	send := &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", configured: spec, spec: spec}
+	useCatchUp(t, send, map[string]time.Time{})
+	send.overlapPolicy = OverlapFail
+	if ok, err := send.lock.TryLock(); !ok || err != nil {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+	defer send.lock.Unlock()
+
+	date := time.Date(2026, 10, 19, 0, 0, 0, 0, util.Location())
+	if err := runScheduled(send, runs.TriggerSchedule, batch.RunOptions{BusinessDate: date}); err != ErrBusy {
+		t.Fatalf("runScheduled() error = %v, want %v", err, ErrBusy)
+	}
+	list, err := runStore.List(runs.Filter{Batch: send.batch, Job: send.name})
+	if err != nil || len(list) != 1 || list[0].State != runs.StateRejected {
+		t.Fatalf("runs = %v, %v, want one rejected run", list, err)
+	}
+	if done, err := upstreamDone(send, "2026-10-19"); done || err != nil {
+		t.Errorf("upstreamDone() = %v, %v, want false after a rejected send", done, err)
+	}
+}
+
+func TestApplyDependency(t *testing.T) {
+	logger.AppLogger = zap.NewNop()
+	sched := config.ScheduleConfig{ResultWaitsSend: true}
+	tests := []struct {
+		name    string
+		watched bool
+		waits   bool
+	}{
+		{"scheduled send", false, true},
+		{"watched send", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			send := &job{batch: "spending_alert", name: JobSend, watched: tt.watched}
+			result := &job{batch: "spending_alert", name: JobResult}
+			applyDependency(sched, send, result)
+			if (result.dependsOn != nil) != tt.waits {
+				t.Errorf("dependsOn = %v, want waiting %v", result.dependsOn, tt.waits)
+			}
+			if tt.waits && result.dependencyTimeout != defaultDependencyTimeout {
+				t.Errorf("dependencyTimeout = %s, want %s", result.dependencyTimeout, defaultDependencyTimeout)
+			}
+		})
+	}
+}
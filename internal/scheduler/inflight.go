@@ -0,0 +1,50 @@
+package scheduler
+
+import "sync"
+
+// inFlight counts the scheduler goroutines in progress (batch runs, catch-up and watchers) so
+// shutdown can wait for them. Once stopped it rejects new work, so nothing can start after
+// shutdown has begun waiting.
+type inFlight struct {
+	mu      sync.Mutex
+	count   int
+	stopped bool
+	idle    chan struct{}
+}
+
+// begin registers new work and reports false if the tracker is stopped; end must be called
+// once the work is done if it returns true.
+func (f *inFlight) begin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return false
+	}
+	f.count++
+	return true
+}
+
+// end marks registered work as done.
+func (f *inFlight) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count--
+	if f.count == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// stop rejects new work and returns a channel that is closed once the work in progress is done.
+func (f *inFlight) stop() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	idle := make(chan struct{})
+	if f.count == 0 {
+		close(idle)
+	} else {
+		f.idle = idle
+	}
+	return idle
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInFlightStopWaitsForWork(t *testing.T) {
+	var f inFlight
+	if !f.begin() || !f.begin() {
+		t.Fatal("begin() = false before stop")
+	}
+
+	done := f.stop()
+	if f.begin() {
+		t.Error("begin() = true after stop")
+	}
+
+	f.end()
+	select {
+	case <-done:
+		t.Fatal("stop() finished with work still in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	f.end()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stop() did not finish after the work ended")
+	}
+}
+
+func TestInFlightStopWhenIdle(t *testing.T) {
+	var f inFlight
+	select {
+	case <-f.stop():
+	default:
+		t.Fatal("stop() with no work in progress did not finish immediately")
+	}
+}
+
+func TestInFlightConcurrentStop(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		var f inFlight
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		active := 0
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !f.begin() {
+					return
+				}
+				mu.Lock()
+				active++
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				active--
+				mu.Unlock()
+				f.end()
+			}()
+		}
+		<-f.stop()
+		mu.Lock()
+		stillActive := active
+		mu.Unlock()
+		if stillActive != 0 {
+			t.Fatalf("%d runs still in progress after stop() finished", stillActive)
+		}
+		wg.Wait()
+	}
+}
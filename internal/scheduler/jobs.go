@@ -0,0 +1,468 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/batch/encb"
+	"notification_batch/internal/batch/spending_alert"
+	"notification_batch/internal/calendar"
+	"notification_batch/internal/config"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/lock"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+
+	"github.com/go-co-op/gocron"
+)
+
+// Job names.
+const (
+	JobSend   = "send"
+	JobResult = "result"
+)
+
+// Overlap policies applied when a job is triggered while a run of it is in progress.
+const (
+	OverlapSkip  = "skip"
+	OverlapQueue = "queue"
+	OverlapFail  = "fail"
+)
+
+// ErrUnknownJob is returned when managing a job that does not exist.
+var ErrUnknownJob = errors.New("unknown job")
+
+// ErrBusy is returned when a job is triggered while a run of it is in progress.
+var ErrBusy = errors.New("job is already running")
+
+var (
+	// mu guards jobs, shifted runs and changes to the gocron schedule.
+	mu            sync.Mutex
+	jobs          []*job
+	overridesFile string
+)
+
+// Spec is when a job runs: on a cron expression if set, otherwise daily at each of Times.
+type Spec struct {
+	Cron  string   `json:"cron,omitempty"`
+	Times []string `json:"times,omitempty"`
+}
+
+// job is a batch job that runs on schedule or on demand.
+type job struct {
+	batch         string
+	name          string
+	label         string
+	configured    Spec
+	spec          Spec
+	paused        bool
+	weekdaysOnly  bool
+	calendar      *calendar.Calendar
+	holidayPolicy string
+	overlapPolicy string
+	misfirePolicy string
+	// dependsOn must have run for the business date before a scheduled run of the job starts.
+	dependsOn         *job
+	dependencyTimeout time.Duration
+	// watched jobs run for new files found by a watcher instead of on their schedule.
+	watched bool
+	lock    *lock.Lock
+	cfg     *config.Config
+	run     func(context.Context, *config.Config, batch.RunOptions) error
+}
+
+// tag identifies the job, e.g. "spending_alert.send".
+func (j *job) tag() string {
+	return j.batch + "." + j.name
+}
+
+// JobInfo describes a job and its effective schedule; Name is also its gocron tag.
+type JobInfo struct {
+	Name          string
+	Batch         string
+	Job           string
+	Spec          Spec
+	WeekdaysOnly  bool
+	HolidayPolicy string
+	OverlapPolicy string
+	MisfirePolicy string
+	DependsOn     string
+	Watched       bool
+	Paused        bool
+}
+
+func (j *job) info() JobInfo {
+	var dependsOn string
+	if j.dependsOn != nil {
+		dependsOn = j.dependsOn.tag()
+	}
+	return JobInfo{
+		Name:          j.tag(),
+		Batch:         j.batch,
+		Job:           j.name,
+		Spec:          j.spec,
+		WeekdaysOnly:  j.weekdaysOnly,
+		HolidayPolicy: j.holidayPolicy,
+		OverlapPolicy: j.overlapPolicy,
+		MisfirePolicy: j.misfirePolicy,
+		DependsOn:     dependsOn,
+		Watched:       j.watched,
+		Paused:        j.paused,
+	}
+}
+
+func setupBatchJobs(cfgMap map[string]*config.Config, overrides map[string]override) error {
+	// Spending Alert Send and Result
+	if cfg, ok := cfgMap["spending_alert"]; ok {
+		sched := cfg.SpendingAlert.Schedule
+		send := &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", spec: newSpec(sched.SendCron, sched.SendTimes, sched.SendTime), misfirePolicy: sched.SendMisfire, cfg: cfg, run: spending_alert.RunSpendingAlertSendBatch}
+		result := &job{batch: "spending_alert", name: JobResult, label: "Spending Alert Result Batch", spec: newSpec(sched.ResultCron, sched.ResultTimes, sched.ResultTime), misfirePolicy: sched.ResultMisfire, cfg: cfg, run: spending_alert.RunSpendingAlertResultBatch}
+		if err := applyCalendar(sched, send, result); err != nil {
+			return fmt.Errorf("spending_alert: %v", err)
+		}
+		if err := applyOverlap(sched, cfg.SpendingAlert.FTP.LocalPath, send, result); err != nil {
+			return fmt.Errorf("spending_alert: %v", err)
+		}
+		if err := applyWatch(cfg.SpendingAlert.Watch, cfg.SpendingAlert.FTP, cfg.DataPath, send); err != nil {
+			return fmt.Errorf("spending_alert: %v", err)
+		}
+		applyDependency(sched, send, result)
+		jobs = append(jobs, send, result)
+	}
+
+	// e-NCB Send and Result
+	if cfg, ok := cfgMap["encb"]; ok {
+		sched := cfg.ENCB.Schedule
+		send := &job{batch: "encb", name: JobSend, label: "e-NCB Send Batch", spec: newSpec(sched.SendCron, sched.SendTimes, sched.SendTime), misfirePolicy: sched.SendMisfire, cfg: cfg, run: encb.RunENCBSendBatch}
+		result := &job{batch: "encb", name: JobResult, label: "e-NCB Result Batch", spec: newSpec(sched.ResultCron, sched.ResultTimes, sched.ResultTime), misfirePolicy: sched.ResultMisfire, cfg: cfg, run: encb.RunENCBResultBatch}
+		if err := applyCalendar(sched, send, result); err != nil {
+			return fmt.Errorf("e_ncb: %v", err)
+		}
+		if err := applyOverlap(sched, cfg.ENCB.FTP.LocalPath, send, result); err != nil {
+			return fmt.Errorf("e_ncb: %v", err)
+		}
+		if err := applyWatch(cfg.ENCB.Watch, cfg.ENCB.FTP, cfg.DataPath, send); err != nil {
+			return fmt.Errorf("e_ncb: %v", err)
+		}
+		applyDependency(sched, send, result)
+		jobs = append(jobs, send, result)
+	}
+
+	for _, j := range jobs {
+		if j.misfirePolicy == "" {
+			j.misfirePolicy = MisfireRunOnce
+		}
+		if !validMisfirePolicy(j.misfirePolicy) {
+			return fmt.Errorf("invalid misfire policy '%s' for job '%s', expected '%s', '%s' or '%s'", j.misfirePolicy, j.tag(), MisfireRunOnce, MisfireRunAll, MisfireSkip)
+		}
+		if j.name == JobSend && j.misfirePolicy == MisfireRunAll {
+			// Every catch-up run would process the same remote files again.
+			return fmt.Errorf("misfire policy '%s' is not supported for job '%s', expected '%s' or '%s'", MisfireRunAll, j.tag(), MisfireRunOnce, MisfireSkip)
+		}
+		j.configured = j.spec
+		if o, ok := overrides[j.tag()]; ok {
+			j.paused = o.Paused
+			if o.Spec != nil {
+				j.spec = *o.Spec
+			}
+			logger.AppLogger.Sugar().Infow("Applied job override", "job", j.tag(), "cron", j.spec.Cron, "times", j.spec.Times, "paused", j.paused)
+		}
+		if err := schedule(j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newSpec builds a job spec from the batch YAML; times override the single legacy time.
+func newSpec(cron string, times []string, at string) Spec {
+	if cron != "" {
+		return Spec{Cron: cron}
+	}
+	if len(times) == 0 && at != "" {
+		times = []string{at}
+	}
+	return Spec{Times: times}
+}
+
+// applyCalendar sets the weekday and holiday settings of a batch on its jobs.
+func applyCalendar(sched config.ScheduleConfig, jobs ...*job) error {
+	if !calendar.ValidPolicy(sched.HolidayPolicy) {
+		return fmt.Errorf("invalid schedule.holiday_policy '%s', expected '%s' or '%s'", sched.HolidayPolicy, calendar.PolicySkip, calendar.PolicyShift)
+	}
+	var cal *calendar.Calendar
+	if sched.HolidayCalendar != "" {
+		var err error
+		if cal, err = calendar.Get(sched.HolidayCalendar); err != nil {
+			return err
+		}
+	}
+	policy := sched.HolidayPolicy
+	if policy == "" {
+		policy = calendar.PolicySkip
+	}
+	for _, j := range jobs {
+		j.weekdaysOnly = sched.WeekdaysOnly
+		j.calendar = cal
+		j.holidayPolicy = policy
+	}
+	return nil
+}
+
+// applyOverlap sets the overlap policy of a batch on its jobs, each locked through a file in localPath
+// so that processes sharing the folder do not run the same job at once.
+func applyOverlap(sched config.ScheduleConfig, localPath string, jobs ...*job) error {
+	policy := sched.OverlapPolicy
+	switch policy {
+	case "":
+		policy = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapFail:
+	default:
+		return fmt.Errorf("invalid schedule.overlap_policy '%s', expected '%s', '%s' or '%s'", policy, OverlapSkip, OverlapQueue, OverlapFail)
+	}
+	for _, j := range jobs {
+		j.overlapPolicy = policy
+		j.lock = lock.New(filepath.Join(localPath, "."+j.name+".lock"))
+	}
+	return nil
+}
+
+// applyDependency makes the result job of a batch wait for its send job when configured. A
+// watched send job runs only when a file arrives, so the result job does not wait for it.
+func applyDependency(sched config.ScheduleConfig, send, result *job) {
+	if !sched.ResultWaitsSend {
+		return
+	}
+	if send.watched {
+		logger.AppLogger.Sugar().Infof("%s does not wait for %s, which runs in watch mode.", result.label, send.label)
+		return
+	}
+	result.dependsOn = send
+	result.dependencyTimeout = sched.ResultWait * time.Second
+	if result.dependencyTimeout <= 0 {
+		result.dependencyTimeout = defaultDependencyTimeout
+	}
+}
+
+func (s Spec) equal(other Spec) bool {
+	if s.Cron != other.Cron || len(s.Times) != len(other.Times) {
+		return false
+	}
+	for i := range s.Times {
+		if s.Times[i] != other.Times[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks that spec describes at least one valid run time.
+func (s Spec) validate() error {
+	if s.Cron != "" {
+		if len(strings.Fields(s.Cron)) != 5 && !strings.HasPrefix(s.Cron, "@") {
+			return fmt.Errorf("invalid cron expression '%s', expected 5 fields", s.Cron)
+		}
+		return nil
+	}
+	if len(s.Times) == 0 {
+		return fmt.Errorf("no run time configured")
+	}
+	for _, at := range s.Times {
+		if _, err := parseClock(at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crons returns the gocron cron expressions for spec and whether they include seconds.
+func (s Spec) crons(weekdaysOnly bool) ([]string, bool, error) {
+	if err := s.validate(); err != nil {
+		return nil, false, err
+	}
+	if s.Cron != "" {
+		return []string{s.Cron}, false, nil
+	}
+	days := "*"
+	if weekdaysOnly {
+		days = "1-5"
+	}
+	var exprs []string
+	for _, at := range s.Times {
+		clock, _ := parseClock(at)
+		exprs = append(exprs, fmt.Sprintf("%d %d %d * * %s", clock.Second(), clock.Minute(), clock.Hour(), days))
+	}
+	return exprs, true, nil
+}
+
+// parseClock parses a time of day as "HH:MM" or "HH:MM:SS".
+func parseClock(at string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, at); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', expected HH:MM or HH:MM:SS", at)
+}
+
+// schedule adds j to gocron unless it is paused or watched; mu must be held or the scheduler not yet shared.
+func schedule(j *job) error {
+	if j.paused || j.watched {
+		return nil
+	}
+	exprs, withSeconds, err := j.spec.crons(j.weekdaysOnly)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job '%s': %v", j.tag(), err)
+	}
+	for _, expr := range exprs {
+		var s *gocron.Scheduler
+		if withSeconds {
+			s = scheduler.CronWithSeconds(expr)
+		} else {
+			s = scheduler.Cron(expr)
+		}
+		s = s.Tag(j.tag())
+		if j.overlapPolicy == OverlapSkip {
+			// Singleton mode drops runs of the same schedule while one is in progress.
+			s = s.SingletonMode()
+		}
+		if _, err := s.Do(fire, j); err != nil {
+			unschedule(j)
+			return fmt.Errorf("failed to schedule job '%s' on '%s': %v", j.tag(), expr, err)
+		}
+	}
+	return nil
+}
+
+// unschedule removes j from gocron; a paused job is not scheduled and is ignored.
+func unschedule(j *job) {
+	_ = scheduler.RemoveByTag(j.tag())
+}
+
+// fire runs j at a scheduled time, applying its weekday and holiday settings.
+func fire(j *job) {
+	if !running.begin() {
+		return
+	}
+	defer running.end()
+
+	if !isLeader() {
+		logger.AppLogger.Sugar().Debugf("Skipping %s: %v.", j.label, leader.ErrNotLeader)
+		return
+	}
+	now := util.Now()
+	if j.weekdaysOnly && (now.Weekday() == time.Saturday || now.Weekday() == time.Sunday) {
+		logger.AppLogger.Sugar().Infof("Skipping %s on a weekend.", j.label)
+		return
+	}
+	if !j.calendar.Covers(now.Year()) {
+		logger.AppLogger.Sugar().Errorw("Holiday calendar has no holidays for the current year", "alert", "holiday_calendar_outdated", "job", j.tag(), "year", now.Year())
+	}
+	if name, ok := j.calendar.Holiday(now); ok {
+		if j.holidayPolicy == calendar.PolicyShift {
+			shift(j, now, name)
+		} else {
+			logger.AppLogger.Sugar().Infof("Skipping %s on holiday '%s'.", j.label, name)
+		}
+		return
+	}
+	runScheduled(j, runs.TriggerSchedule, batch.RunOptions{})
+}
+
+func findJob(batchName, name string) *job {
+	for _, j := range jobs {
+		if j.batch == batchName && j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// jobByTag returns the job tagged name, or nil; mu must be held.
+func jobByTag(name string) *job {
+	for _, j := range jobs {
+		if j.tag() == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// Jobs returns the batch jobs with their effective schedule.
+func Jobs() []JobInfo {
+	mu.Lock()
+	defer mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		infos = append(infos, j.info())
+	}
+	return infos
+}
+
+// Pause stops the named job from running on schedule; on-demand runs are unaffected.
+func Pause(name string) (JobInfo, error) {
+	return update(name, func(j *job) {
+		j.paused = true
+	})
+}
+
+// Resume schedules a paused job again.
+func Resume(name string) (JobInfo, error) {
+	return update(name, func(j *job) {
+		j.paused = false
+	})
+}
+
+// Reschedule replaces the schedule of the named job with spec.
+func Reschedule(name string, spec Spec) (JobInfo, error) {
+	if err := spec.validate(); err != nil {
+		return JobInfo{}, err
+	}
+	return update(name, func(j *job) {
+		j.spec = spec
+	})
+}
+
+// update applies change to the named job, reschedules it and persists the overrides.
+// Only the leader changes jobs; a standby loads the overrides when it takes over.
+func update(name string, change func(*job)) (JobInfo, error) {
+	mu.Lock()
+	j := jobByTag(name)
+	if j == nil {
+		mu.Unlock()
+		return JobInfo{}, ErrUnknownJob
+	}
+	if !isLeader() {
+		mu.Unlock()
+		return JobInfo{}, leader.ErrNotLeader
+	}
+
+	previous := *j
+	change(j)
+	unschedule(j)
+	err := schedule(j)
+	if err == nil {
+		err = saveOverrides(overridesFile, jobs)
+	}
+	if err != nil {
+		// Keep the running schedule in line with the persisted one.
+		unschedule(j)
+		*j = previous
+		_ = schedule(j)
+		mu.Unlock()
+		return JobInfo{}, err
+	}
+	info := j.info()
+	mu.Unlock()
+
+	logger.AppLogger.Sugar().Infow("Job schedule changed", "job", info.Name, "cron", info.Spec.Cron, "times", info.Spec.Times, "paused", info.Paused)
+	return info, nil
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// useTimezone sets the business time zone for the duration of the test.
+func useTimezone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	previous := util.Location()
+	if err := util.SetTimezone(name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { util.SetTimezone(previous.String()) })
+	return util.Location()
+}
+
+// useJobs installs js on a fresh gocron scheduler for the duration of the test.
+func useJobs(t *testing.T, js ...*job) {
+	t.Helper()
+	logger.AppLogger = zap.NewNop()
+	prevScheduler, prevJobs, prevFile := scheduler, jobs, overridesFile
+	scheduler = newScheduler()
+	jobs = js
+	overridesFile = filepath.Join(t.TempDir(), "overrides.json")
+	for _, j := range js {
+		if err := schedule(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		scheduler, jobs, overridesFile = prevScheduler, prevJobs, prevFile
+	})
+}
+
+// scheduled returns the number of gocron jobs tagged tag.
+func scheduled(tag string) int {
+	n := 0
+	for _, j := range scheduler.Jobs() {
+		for _, jt := range j.Tags() {
+			if jt == tag {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestUpdate(t *testing.T) {
+	configured := Spec{Times: []string{"08:00"}}
+	j := &job{batch: "spending_alert", name: JobSend, configured: configured, spec: configured}
+	useJobs(t, j)
+
+	info, err := Reschedule(j.tag(), Spec{Times: []string{"09:00", "17:00"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Spec.Times) != 2 || scheduled(j.tag()) != 2 {
+		t.Fatalf("Reschedule() = %+v, scheduled %d", info, scheduled(j.tag()))
+	}
+	overrides, err := loadOverrides(overridesFile)
+	if err != nil || overrides[j.tag()].Spec == nil {
+		t.Fatalf("overrides after Reschedule = %+v, %v", overrides, err)
+	}
+
+	if _, err := Pause(j.tag()); err != nil || scheduled(j.tag()) != 0 {
+		t.Fatalf("Pause() error = %v, scheduled %d", err, scheduled(j.tag()))
+	}
+	if _, err := Pause("missing.send"); err != ErrUnknownJob {
+		t.Errorf("Pause() of an unknown job error = %v, want ErrUnknownJob", err)
+	}
+}
+
+func TestUpdateRestoresJobWhenSaveFails(t *testing.T) {
+	configured := Spec{Times: []string{"08:00"}}
+	j := &job{batch: "spending_alert", name: JobSend, configured: configured, spec: configured}
+	useJobs(t, j)
+	// A file in place of the overrides directory makes every save fail.
+	blocker := filepath.Join(t.TempDir(), "scheduler")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	overridesFile = filepath.Join(blocker, "overrides.json")
+
+	if _, err := Pause(j.tag()); err == nil {
+		t.Fatal("Pause() error = nil, want the save error")
+	}
+	if j.paused || scheduled(j.tag()) != 1 {
+		t.Errorf("after a failed Pause: paused = %v, scheduled %d", j.paused, scheduled(j.tag()))
+	}
+
+	if _, err := Reschedule(j.tag(), Spec{Times: []string{"09:00", "17:00"}}); err == nil {
+		t.Fatal("Reschedule() error = nil, want the save error")
+	}
+	if !j.spec.equal(configured) || scheduled(j.tag()) != 1 {
+		t.Errorf("after a failed Reschedule: spec = %+v, scheduled %d", j.spec, scheduled(j.tag()))
+	}
+}
+
+func TestScheduleUsesBusinessTimezone(t *testing.T) {
+	loc := useTimezone(t, "Asia/Bangkok")
+	spec := Spec{Times: []string{"08:00"}}
+	j := &job{batch: "spending_alert", name: JobSend, configured: spec, spec: spec}
+	useJobs(t, j)
+	scheduler.StartAsync()
+	t.Cleanup(scheduler.Stop)
+
+	if n := scheduled(j.tag()); n != 1 {
+		t.Fatalf("scheduled %d jobs, want 1", n)
+	}
+	for _, scheduledJob := range scheduler.Jobs() {
+		next := scheduledJob.NextRun().In(loc)
+		if next.Hour() != 8 || next.Minute() != 0 {
+			t.Errorf("NextRun() = %s, want 08:00 in %s", next, loc)
+		}
+	}
+}
+
+// useLeader makes this replica the leader or a standby for the duration of the test.
+func useLeader(t *testing.T, leading bool) {
+	t.Helper()
+	previous := isLeader
+	isLeader = func() bool { return leading }
+	t.Cleanup(func() { isLeader = previous })
+}
+
+func TestOnlyLeaderChangesJobs(t *testing.T) {
+	configured := Spec{Times: []string{"08:00"}}
+	leaderJob := &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", configured: configured, spec: configured}
+	standbyJob := *leaderJob
+	path := filepath.Join(t.TempDir(), "overrides.json")
+
+	// The standby refuses changes, which would be lost when the leader saves its own jobs.
+	useJobs(t, &standbyJob)
+	overridesFile = path
+	useLeader(t, false)
+	if _, err := Pause(standbyJob.tag()); !errors.Is(err, leader.ErrNotLeader) {
+		t.Fatalf("Pause() on the standby error = %v, want ErrNotLeader", err)
+	}
+	if _, err := Reschedule(standbyJob.tag(), Spec{Times: []string{"09:00"}}); !errors.Is(err, leader.ErrNotLeader) {
+		t.Fatalf("Reschedule() on the standby error = %v, want ErrNotLeader", err)
+	}
+	if standbyJob.paused || !standbyJob.spec.equal(configured) {
+		t.Fatalf("standby job changed: paused = %v, spec = %+v", standbyJob.paused, standbyJob.spec)
+	}
+
+	// The leader pauses the job.
+	useJobs(t, leaderJob)
+	overridesFile = path
+	useLeader(t, true)
+	if _, err := Pause(leaderJob.tag()); err != nil {
+		t.Fatalf("Pause() on the leader error = %v", err)
+	}
+
+	// The standby takes over and keeps the job paused.
+	useJobs(t, &standbyJob)
+	overridesFile = path
+	reloadOverrides()
+	if !standbyJob.paused || scheduled(standbyJob.tag()) != 0 {
+		t.Errorf("after takeover: paused = %v, scheduled %d, want the leader's pause", standbyJob.paused, scheduled(standbyJob.tag()))
+	}
+}
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"notification_batch/internal/logger"
+	"notification_batch/internal/util"
+)
+
+// override is a runtime change to a job's configured schedule, persisted across restarts.
+type override struct {
+	Paused bool  `json:"paused,omitempty"`
+	Spec   *Spec `json:"spec,omitempty"`
+}
+
+// overridesPath returns the file holding job overrides under dataPath.
+func overridesPath(dataPath string) string {
+	return filepath.Join(dataPath, "scheduler", "overrides.json")
+}
+
+func loadOverrides(path string) (map[string]override, error) {
+	overrides := make(map[string]override)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return overrides, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job overrides '%s': %v", path, err)
+	}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job overrides '%s': %v", path, err)
+	}
+	return overrides, nil
+}
+
+// saveOverrides writes the overrides of all jobs atomically.
+func saveOverrides(path string, jobs []*job) error {
+	overrides := make(map[string]override)
+	for _, j := range jobs {
+		o := override{Paused: j.paused}
+		if !j.spec.equal(j.configured) {
+			spec := j.spec
+			o.Spec = &spec
+		}
+		if o.Paused || o.Spec != nil {
+			overrides[j.tag()] = o
+		}
+	}
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job overrides: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for job overrides '%s': %v", path, err)
+	}
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write job overrides '%s': %v", path, err)
+	}
+	return nil
+}
+
+// reloadOverrides applies the overrides saved by the previous leader, which may have changed jobs
+// after this replica loaded them at startup.
+func reloadOverrides() {
+	overrides, err := loadOverrides(overridesFile)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to reload job overrides: %v", err)
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, j := range jobs {
+		o := overrides[j.tag()]
+		spec := j.configured
+		if o.Spec != nil {
+			spec = *o.Spec
+		}
+		if j.paused == o.Paused && j.spec.equal(spec) {
+			continue
+		}
+		j.paused, j.spec = o.Paused, spec
+		unschedule(j)
+		if err := schedule(j); err != nil {
+			logger.AppLogger.Sugar().Errorf("Failed to apply reloaded override of %s: %v", j.label, err)
+			continue
+		}
+		logger.AppLogger.Sugar().Infow("Applied job override", "job", j.tag(), "cron", j.spec.Cron, "times", j.spec.Times, "paused", j.paused)
+	}
+}
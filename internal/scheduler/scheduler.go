@@ -1,84 +1,318 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
-	"notification_batch/internal/batch/encb"
-	"notification_batch/internal/batch/spending_alert"
+	"notification_batch/internal/batch"
 	"notification_batch/internal/config"
+	"notification_batch/internal/correlation"
+	"notification_batch/internal/leader"
 	"notification_batch/internal/logger"
+	"notification_batch/internal/metrics"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
 
 	"github.com/go-co-op/gocron"
 )
 
+// ErrUnknownBatch is returned when triggering a batch that is not configured.
+var ErrUnknownBatch = errors.New("unknown batch")
+
+// ErrStopped is returned when triggering a batch after the scheduler has been stopped.
+var ErrStopped = errors.New("scheduler is stopped")
+
 var (
 	scheduler *gocron.Scheduler
 	once      sync.Once
+	runStore  *runs.Store
+
+	// ctx is passed to every batch run; cancel interrupts running batches on shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// runRetention is how long the run history is kept.
+	runRetention time.Duration
+
+	// running tracks batch runs, catch-up and watchers in progress so shutdown can wait for them.
+	running inFlight
+
+	// isLeader reports whether this replica runs jobs and may change them.
+	isLeader = leader.IsLeader
 )
 
 // InitScheduler initializes the scheduler and defines the batch jobs.
-func InitScheduler(cfgMap map[string]*config.Config) {
+func InitScheduler(cfgMap map[string]*config.Config) error {
+	var err error
 	once.Do(func() {
-		scheduler = gocron.NewScheduler(time.Local)
-		setupBatchJobs(cfgMap)
+		runStore, err = runs.Open(runs.RunsPath(cfgMap["default"].DataPath))
+		if err != nil {
+			return
+		}
+		runRetention = time.Duration(cfgMap["default"].RunRetentionDays) * 24 * time.Hour
+		pruneRuns()
+		overridesFile = overridesPath(cfgMap["default"].DataPath)
+		var overrides map[string]override
+		overrides, err = loadOverrides(overridesFile)
+		if err != nil {
+			return
+		}
+		shiftedFile = shiftedPath(cfgMap["default"].DataPath)
+		lastSuccessFile = lastSuccessPath(cfgMap["default"].DataPath)
+		lastSuccess, err = loadLastSuccess(lastSuccessFile)
+		if err != nil {
+			return
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		scheduler = newScheduler()
+		if err = setupBatchJobs(cfgMap, overrides); err != nil {
+			return
+		}
+		reportLastSuccess()
 	})
+	return err
+}
+
+// newScheduler returns a gocron scheduler running jobs in the business time zone.
+func newScheduler() *gocron.Scheduler {
+	s := gocron.NewScheduler(util.Location())
+	// Cron jobs would otherwise run once as soon as they are added.
+	s.WaitForScheduleAll()
+	return s
+}
+
+// Get returns the underlying gocron scheduler, or nil before InitScheduler.
+func Get() *gocron.Scheduler {
+	return scheduler
 }
 
-// StartScheduler starts the scheduler in a non-blocking way.
+// StartScheduler starts the scheduler in a non-blocking way and catches up on runs missed
+// while the application was down.
 func StartScheduler() {
 	if scheduler != nil {
 		scheduler.StartAsync()
+		if running.begin() {
+			go catchUp()
+		}
+		for _, w := range watchers {
+			if running.begin() {
+				go w.run()
+			}
+		}
 		logger.AppLogger.Info("Scheduler started.")
 	} else {
 		logger.AppLogger.Warn("Scheduler not initialized.")
 	}
 }
 
-// StopScheduler stops the scheduler gracefully.
-func StopScheduler() {
-	if scheduler != nil {
-		scheduler.Stop()
+// StopScheduler stops the scheduler, cancels running batches and waits until they reach a
+// checkpoint. It returns an error if shutdownCtx expires before the batches have stopped.
+func StopScheduler(shutdownCtx context.Context) error {
+	if scheduler == nil {
+		logger.AppLogger.Warn("Scheduler not initialized.")
+		return nil
+	}
+	// Reject new runs before cancelling, so none starts with a cancelled context.
+	done := running.stop()
+	cancel()
+	scheduler.Stop()
+	stopShiftedRuns()
+
+	select {
+	case <-done:
 		logger.AppLogger.Info("Scheduler stopped.")
+		return nil
+	case <-shutdownCtx.Done():
+		return fmt.Errorf("timed out waiting for running batches to stop: %v", shutdownCtx.Err())
+	}
+}
+
+// Trigger starts the send job of the named batch in the background and returns its run,
+// which is recorded in the run history like scheduled runs.
+func Trigger(batchName string, opts batch.RunOptions) (*runs.Run, error) {
+	if scheduler == nil {
+		return nil, ErrStopped
+	}
+	mu.Lock()
+	j := findJob(batchName, JobSend)
+	mu.Unlock()
+	if j == nil {
+		return nil, ErrUnknownBatch
+	}
+	if !isLeader() {
+		return nil, leader.ErrNotLeader
+	}
+	// The run is registered before its goroutine starts so shutdown cannot miss it.
+	if !running.begin() {
+		return nil, ErrStopped
+	}
+
+	locked, err := j.lock.TryLock()
+	if err != nil {
+		running.end()
+		return nil, err
+	}
+	if !locked {
+		if err := overlap(j, runs.TriggerAPI, opts); err != nil {
+			running.end()
+			return nil, err
+		}
+	}
+	r, err := startRun(j, runs.TriggerAPI, opts)
+	if err != nil {
+		if locked {
+			unlock(j)
+		}
+		running.end()
+		return nil, err
+	}
+	go func() {
+		defer running.end()
+		executeLocked(j, r, opts, locked)
+	}()
+	return r, nil
+}
+
+// runScheduled records and executes a run of j started by the scheduler, once its upstream job
+// has completed for the same business date. It returns ErrBusy if the run was rejected by the
+// overlap policy of j, or the error the run failed with.
+func runScheduled(j *job, trigger string, opts batch.RunOptions) error {
+	if j.dependsOn != nil {
+		if err := waitForUpstream(j, opts); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.AppLogger.Sugar().Errorw("Upstream job did not complete", "alert", "dependency_timeout", "job", j.tag(), "depends_on", j.dependsOn.tag(), "business_date", opts.Date().Format("2006-01-02"), "error", err)
+			failRun(j, trigger, opts, err)
+			return err
+		}
+	}
+	locked, err := j.lock.TryLock()
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to start %s: %v", j.label, err)
+		return err
+	}
+	if !locked {
+		if err := overlap(j, trigger, opts); err != nil {
+			return err
+		}
+	}
+	r, err := startRun(j, trigger, opts)
+	if err != nil {
+		if locked {
+			unlock(j)
+		}
+		logger.AppLogger.Sugar().Errorf("Failed to start %s: %v", j.label, err)
+		return err
+	}
+	return executeLocked(j, r, opts, locked)
+}
+
+// overlap applies the overlap policy of j to a run triggered while another run of it is in
+// progress. It returns ErrBusy unless the run should wait for the lock.
+func overlap(j *job, trigger string, opts batch.RunOptions) error {
+	switch j.overlapPolicy {
+	case OverlapQueue:
+		logger.AppLogger.Sugar().Infof("%s (from %s) is queued behind the run in progress.", j.label, trigger)
+		return nil
+	case OverlapFail:
+		failRun(j, trigger, opts, ErrBusy)
+	default:
+		logger.AppLogger.Sugar().Warnf("Skipping %s (from %s): %v.", j.label, trigger, ErrBusy)
+	}
+	return ErrBusy
+}
+
+// failRun records a run of j rejected with cause before it could start.
+func failRun(j *job, trigger string, opts batch.RunOptions, cause error) {
+	r, err := startRun(j, trigger, opts)
+	if err == nil {
+		r.Reject(cause)
+		err = runStore.Save(r)
+	}
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record rejected run of %s: %v", j.label, err)
+	}
+	logger.AppLogger.Sugar().Errorf("%s (from %s) failed: %v", j.label, trigger, cause)
+}
+
+// executeLocked executes r while holding the lock of j, first waiting for it unless already locked.
+func executeLocked(j *job, r *runs.Run, opts batch.RunOptions, locked bool) error {
+	if !locked {
+		if err := j.lock.Lock(ctx); err != nil {
+			r.Reject(fmt.Errorf("stopped while waiting for the run in progress: %v", err))
+			if err := runStore.Save(r); err != nil {
+				logger.AppLogger.Sugar().Errorf("Failed to record run '%s': %v", r.ID, err)
+			}
+			return err
+		}
+	}
+	defer unlock(j)
+	return execute(j, r, opts)
+}
+
+func unlock(j *job) {
+	if err := j.lock.Unlock(); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to unlock %s: %v", j.label, err)
+	}
+}
+
+// startRun records a new running run of j.
+func startRun(j *job, trigger string, opts batch.RunOptions) (*runs.Run, error) {
+	r := &runs.Run{
+		ID:           correlation.NewRunID(j.batch),
+		Batch:        j.batch,
+		Job:          j.name,
+		Trigger:      trigger,
+		FileName:     opts.FileName,
+		BusinessDate: opts.Date().Format("2006-01-02"),
+		State:        runs.StateRunning,
+		StartedAt:    time.Now(),
+		Files:        []*runs.FileResult{},
+		Counts:       map[string]int{},
+	}
+	if err := runStore.Save(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// execute runs j under the scheduler context, records the outcome in r and returns the run error.
+func execute(j *job, r *runs.Run, opts batch.RunOptions) error {
+	runCtx := runs.WithRecorder(correlation.WithRunID(ctx, r.ID), runs.NewRecorder(runStore, r))
+	log := logger.FromContext(runCtx)
+
+	log.Infof("Starting %s (from %s)...", j.label, r.Trigger)
+	err := j.run(runCtx, j.cfg, opts)
+	r.Finish(err)
+	metrics.ObserveJobRun(j.batch, j.name, r.State, r.EndedAt.Sub(r.StartedAt))
+	if err == nil {
+		metrics.SetJobLastSuccess(j.batch, j.name, *r.EndedAt)
+	}
+	if err == nil && r.Trigger != runs.TriggerAPI {
+		recordSuccess(j, util.Now())
+	}
+	if err != nil {
+		log.Errorf("%s (from %s) failed: %v", j.label, r.Trigger, err)
 	} else {
-		logger.AppLogger.Warn("Scheduler not initialized.")
+		log.Infof("%s (from %s) finished.", j.label, r.Trigger)
+	}
+	if saveErr := runStore.Save(r); saveErr != nil {
+		log.Errorf("Failed to record run '%s': %v", r.ID, saveErr)
 	}
+	pruneRuns()
+	return err
 }
 
-func setupBatchJobs(cfgMap map[string]*config.Config) {
-	// Spending Alert Send
-	if cfg, ok := cfgMap["spending_alert"]; ok {
-		scheduler.Every(1).Day().At(cfg.SpendingAlert.Schedule.SendTime).Do(func() {
-			logger.AppLogger.Info("Starting Spending Alert Send Batch (from scheduler)...")
-			spending_alert.RunSpendingAlertSendBatch(cfg)
-			logger.AppLogger.Info("Spending Alert Send Batch (from scheduler) finished.")
-		})
-	}
-
-	// Spending Alert Result
-	if cfg, ok := cfgMap["spending_alert"]; ok {
-		scheduler.Every(1).Day().At(cfg.SpendingAlert.Schedule.ResultTime).Do(func() {
-			logger.AppLogger.Info("Starting Spending Alert Result Batch (from scheduler)...")
-			spending_alert.RunSpendingAlertResultBatch(cfg)
-			logger.AppLogger.Info("Spending Alert Result Batch (from scheduler) finished.")
-		})
-	}
-
-	// e-NCB Send
-	if cfg, ok := cfgMap["encb"]; ok {
-		scheduler.Every(1).Day().At(cfg.ENCB.Schedule.SendTime).Do(func() {
-			logger.AppLogger.Info("Starting e-NCB Send Batch (from scheduler)...")
-			encb.RunENCBSendBatch(cfg)
-			logger.AppLogger.Info("e-NCB Send Batch (from scheduler) finished.")
-		})
-	}
-
-	// e-NCB Result
-	if cfg, ok := cfgMap["encb"]; ok {
-		scheduler.Every(1).Day().At(cfg.ENCB.Schedule.ResultTime).Do(func() {
-			logger.AppLogger.Info("Starting e-NCB Result Batch (from scheduler)...")
-			encb.RunENCBResultBatch(cfg)
-			logger.AppLogger.Info("e-NCB Result Batch (from scheduler) finished.")
-		})
+// pruneRuns removes run history older than the retention period.
+func pruneRuns() {
+	removed, err := runStore.Prune(util.Now().Add(-runRetention))
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to prune run history: %v", err)
+	} else if removed > 0 {
+		logger.AppLogger.Sugar().Infof("Removed run history of %d business dates older than %s.", removed, runRetention)
 	}
 }
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+)
+
+// shiftedRun is a scheduled run moved off a holiday, persisted until it has run so that a
+// restart or a new leader does not lose it.
+type shiftedRun struct {
+	Job          string    `json:"job"`
+	BusinessDate time.Time `json:"business_date"`
+	Holiday      string    `json:"holiday"`
+	At           time.Time `json:"at"`
+	timer        *time.Timer
+}
+
+var (
+	// shifted holds the pending shifted runs; it is guarded by mu.
+	shifted     []*shiftedRun
+	shiftedFile string
+)
+
+// shiftedPath returns the file holding pending shifted runs under dataPath.
+func shiftedPath(dataPath string) string {
+	return filepath.Join(dataPath, "scheduler", "shifted.json")
+}
+
+func loadShifted(path string) ([]*shiftedRun, error) {
+	var pending []*shiftedRun
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shifted runs '%s': %v", path, err)
+	}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal shifted runs '%s': %v", path, err)
+	}
+	return pending, nil
+}
+
+// saveShifted writes the pending shifted runs atomically.
+func saveShifted(path string, pending []*shiftedRun) error {
+	if pending == nil {
+		pending = []*shiftedRun{}
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal shifted runs: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for shifted runs '%s': %v", path, err)
+	}
+	if err := util.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write shifted runs '%s': %v", path, err)
+	}
+	return nil
+}
+
+// shift runs j at the same time on the next business day, keeping the holiday as its business
+// date. A run already shifted off the same holiday is not shifted again.
+func shift(j *job, holiday time.Time, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if findShifted(j.tag(), holiday) != nil {
+		return
+	}
+
+	s := &shiftedRun{Job: j.tag(), BusinessDate: holiday, Holiday: name, At: j.calendar.NextBusinessDay(holiday, j.weekdaysOnly)}
+	logger.AppLogger.Sugar().Infof("Shifting %s from holiday '%s' to %s.", j.label, name, s.At.Format(time.RFC3339))
+	shifted = append(shifted, s)
+	if err := saveShifted(shiftedFile, shifted); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record shifted run of %s: %v", j.label, err)
+	}
+	arm(j, s)
+}
+
+// arm starts the timer of s, which runs j immediately if s is already due; mu must be held.
+func arm(j *job, s *shiftedRun) {
+	s.timer = time.AfterFunc(time.Until(s.At), func() {
+		if !running.begin() {
+			return
+		}
+		defer running.end()
+		if !isLeader() {
+			// The leader runs it from the shifted runs it loaded when elected.
+			return
+		}
+		mu.Lock()
+		paused := j.paused
+		mu.Unlock()
+		if paused {
+			logger.AppLogger.Sugar().Infof("Dropping %s shifted from holiday '%s': the job is paused.", j.label, s.Holiday)
+		} else {
+			runScheduled(j, runs.TriggerHolidayShift, batch.RunOptions{BusinessDate: s.BusinessDate})
+		}
+		if ctx.Err() != nil {
+			// Interrupted by shutdown; run it again after the restart.
+			return
+		}
+		finishShifted(s)
+	})
+}
+
+// finishShifted removes s from the pending shifted runs once it has run.
+func finishShifted(s *shiftedRun) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, pending := range shifted {
+		if pending == s {
+			shifted = append(shifted[:i], shifted[i+1:]...)
+			break
+		}
+	}
+	if err := saveShifted(shiftedFile, shifted); err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record shifted runs: %v", err)
+	}
+}
+
+// restoreShifted loads the shifted runs persisted by earlier leaders; they are started by
+// armShifted so that catch-up does not shift the same runs again.
+func restoreShifted() {
+	pending, err := loadShifted(shiftedFile)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to load shifted runs: %v", err)
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range pending {
+		if findShifted(s.Job, s.BusinessDate) == nil {
+			shifted = append(shifted, s)
+		}
+	}
+}
+
+// armShifted starts the timers of restored shifted runs; runs of unknown jobs are dropped.
+func armShifted() {
+	mu.Lock()
+	defer mu.Unlock()
+	kept := shifted[:0]
+	for _, s := range shifted {
+		j := jobByTag(s.Job)
+		if j == nil {
+			logger.AppLogger.Sugar().Warnf("Dropping run of unknown job '%s' shifted from holiday '%s'.", s.Job, s.Holiday)
+			continue
+		}
+		kept = append(kept, s)
+		if s.timer == nil {
+			arm(j, s)
+		}
+	}
+	if len(kept) != len(shifted) {
+		shifted = kept
+		if err := saveShifted(shiftedFile, shifted); err != nil {
+			logger.AppLogger.Sugar().Errorf("Failed to record shifted runs: %v", err)
+		}
+	}
+}
+
+// findShifted returns the pending run of job shifted off businessDate; mu must be held.
+func findShifted(job string, businessDate time.Time) *shiftedRun {
+	for _, s := range shifted {
+		if s.Job == job && s.BusinessDate.Equal(businessDate) {
+			return s
+		}
+	}
+	return nil
+}
+
+// stopShiftedRuns cancels shifted runs that have not started yet; they stay persisted.
+func stopShiftedRuns() {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range shifted {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+	}
+	shifted = nil
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification_batch/internal/calendar"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// holidayJob returns a weekday job whose calendar lists holiday.
+func holidayJob(t *testing.T, holiday time.Time) *job {
+	t.Helper()
+	logger.AppLogger = zap.NewNop()
+	path := filepath.Join(t.TempDir(), "holidays.yaml")
+	content := "holidays:\n  - date: \"" + holiday.Format("2006-01-02") + "\"\n    name: \"Test Holiday\"\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := calendar.Get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := Spec{Times: []string{"08:00"}}
+	return &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", configured: spec, spec: spec,
+		weekdaysOnly: true, calendar: cal, holidayPolicy: calendar.PolicyShift, misfirePolicy: MisfireRunAll}
+}
+
+// nextWeekday returns 08:00 on the first Monday to Thursday at least days after today.
+func nextWeekday(days int) time.Time {
+	now := util.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day()+days, 8, 0, 0, 0, util.Location())
+	for t.Weekday() == time.Friday || t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+func useShifted(t *testing.T) {
+	t.Helper()
+	shiftedFile = filepath.Join(t.TempDir(), "shifted.json")
+	t.Cleanup(stopShiftedRuns)
+}
+
+func TestShiftPersistsRun(t *testing.T) {
+	holiday := nextWeekday(7)
+	j := holidayJob(t, holiday)
+	useJobs(t, j)
+	useShifted(t)
+
+	shift(j, holiday, "Test Holiday")
+	shift(j, holiday, "Test Holiday")
+
+	pending, err := loadShifted(shiftedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("persisted %d shifted runs, want 1", len(pending))
+	}
+	s := pending[0]
+	if s.Job != j.tag() || !s.BusinessDate.Equal(holiday) || !s.At.Equal(holiday.AddDate(0, 0, 1)) {
+		t.Errorf("shifted run = %+v, want %s moved to the next day", s, holiday)
+	}
+}
+
+func TestRestoreShifted(t *testing.T) {
+	holiday := nextWeekday(7)
+	j := holidayJob(t, holiday)
+	useJobs(t, j)
+	useShifted(t)
+	at := holiday.AddDate(0, 0, 1)
+	persisted := []*shiftedRun{
+		{Job: j.tag(), BusinessDate: holiday, Holiday: "Test Holiday", At: at},
+		{Job: "removed.send", BusinessDate: holiday, Holiday: "Test Holiday", At: at},
+	}
+	if err := saveShifted(shiftedFile, persisted); err != nil {
+		t.Fatal(err)
+	}
+
+	restoreShifted()
+	// Catch-up finds the same holiday missed; it must not be shifted twice.
+	shift(j, holiday, "Test Holiday")
+	armShifted()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(shifted) != 1 || shifted[0].timer == nil {
+		t.Fatalf("pending shifted runs = %+v, want the known job armed", shifted)
+	}
+	pending, err := loadShifted(shiftedFile)
+	if err != nil || len(pending) != 1 || pending[0].Job != j.tag() {
+		t.Errorf("persisted shifted runs = %+v, %v", pending, err)
+	}
+}
@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/config"
+	"notification_batch/internal/ftp"
+	"notification_batch/internal/leader"
+	"notification_batch/internal/logger"
+	"notification_batch/internal/runs"
+	"notification_batch/internal/util"
+)
+
+// watchers poll the send folders of batches in watch mode.
+var watchers []*watcher
+
+// watchedFile identifies a version of a remote file.
+type watchedFile struct {
+	Size    uint64    `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (f watchedFile) same(other watchedFile) bool {
+	return f.Size == other.Size && f.ModTime.Equal(other.ModTime)
+}
+
+// candidate is a new file waiting to become stable.
+type candidate struct {
+	file  watchedFile
+	polls int
+}
+
+// watcher polls the send folder of a batch and runs its send job for each new file once the
+// file has stopped changing, instead of the send job's fixed schedule.
+type watcher struct {
+	job         *job
+	ftp         config.FTPConfig
+	interval    time.Duration
+	stablePolls int
+	pattern     string
+	path        string
+	pending     map[string]*candidate
+	processed   map[string]watchedFile
+	// failed holds files whose run failed; they are retried once they change or after a restart.
+	failed map[string]watchedFile
+}
+
+// watchPath returns the file holding the files already processed by the watcher of j under dataPath.
+func watchPath(dataPath string, j *job) string {
+	return filepath.Join(dataPath, "scheduler", "watch", j.tag()+".json")
+}
+
+// applyWatch puts the send job of a batch in watch mode when configured.
+func applyWatch(cfg config.WatchConfig, ftpCfg config.FTPConfig, dataPath string, send *job) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid watch.pattern '%s': %v", cfg.Pattern, err)
+	}
+	w := &watcher{
+		job:         send,
+		ftp:         ftpCfg,
+		interval:    cfg.PollInterval * time.Second,
+		stablePolls: cfg.StablePolls,
+		pattern:     cfg.Pattern,
+		path:        watchPath(dataPath, send),
+		pending:     make(map[string]*candidate),
+		failed:      make(map[string]watchedFile),
+	}
+	if w.interval <= 0 {
+		w.interval = time.Minute
+	}
+	if w.stablePolls <= 0 {
+		w.stablePolls = 1
+	}
+	send.watched = true
+	watchers = append(watchers, w)
+	return nil
+}
+
+// run polls until the scheduler stops. The caller registers it with running.begin.
+func (w *watcher) run() {
+	defer running.end()
+	select {
+	case <-leader.Elected():
+	case <-ctx.Done():
+		return
+	}
+	// The previous leader kept processing files while this replica was on standby.
+	processed, err := loadWatched(w.path)
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Not watching for %s: %v", w.job.label, err)
+		return
+	}
+	w.processed = processed
+	logger.AppLogger.Sugar().Infof("Watching '%s' for %s every %s.", w.ftp.RemotePathSend, w.job.label, w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.poll()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll lists the send folder and runs the send job for each new file that kept the same size
+// and modification time for the configured number of polls.
+func (w *watcher) poll() {
+	mu.Lock()
+	paused := w.job.paused
+	mu.Unlock()
+	if paused {
+		return
+	}
+
+	files, err := w.list()
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to poll '%s' for %s: %v", w.ftp.RemotePathSend, w.job.label, err)
+		return
+	}
+	w.process(files)
+}
+
+// process runs the send job for the stable new files among files, the current folder listing.
+func (w *watcher) process(files []ftp.FileInfo) {
+	present := make(map[string]bool)
+	for _, f := range files {
+		if ok, _ := filepath.Match(w.pattern, f.Name); w.pattern != "" && !ok {
+			continue
+		}
+		present[f.Name] = true
+		seen := watchedFile{Size: f.Size, ModTime: f.ModTime}
+		if done, ok := w.processed[f.Name]; ok && done.same(seen) {
+			continue
+		}
+		if failed, ok := w.failed[f.Name]; ok && failed.same(seen) {
+			continue
+		}
+		c, ok := w.pending[f.Name]
+		if !ok || !c.file.same(seen) {
+			w.pending[f.Name] = &candidate{file: seen}
+			continue
+		}
+		if c.polls++; c.polls < w.stablePolls || ctx.Err() != nil {
+			continue
+		}
+
+		logger.AppLogger.Sugar().Infof("New file '%s' is stable; starting %s.", f.Name, w.job.label)
+		err := runScheduled(w.job, runs.TriggerWatch, batch.RunOptions{FileName: f.Name})
+		if errors.Is(err, ErrBusy) || ctx.Err() != nil {
+			// Picked up again on the next poll.
+			continue
+		}
+		delete(w.pending, f.Name)
+		if err != nil {
+			logger.AppLogger.Sugar().Errorf("%s failed for new file '%s'; it is retried once the file changes or after a restart, or trigger it again: %v", w.job.label, f.Name, err)
+			w.failed[f.Name] = seen
+			continue
+		}
+		delete(w.failed, f.Name)
+		w.processed[f.Name] = seen
+		w.save()
+	}
+
+	// Forget files that are gone so a file with the same name is picked up again.
+	for name := range w.pending {
+		if !present[name] {
+			delete(w.pending, name)
+		}
+	}
+	for name := range w.failed {
+		if !present[name] {
+			delete(w.failed, name)
+		}
+	}
+	changed := false
+	for name := range w.processed {
+		if !present[name] {
+			delete(w.processed, name)
+			changed = true
+		}
+	}
+	if changed {
+		w.save()
+	}
+}
+
+func (w *watcher) list() ([]ftp.FileInfo, error) {
+	client, err := ftp.NewClient(ctx, ftp.Config{
+		Host:     w.ftp.Host,
+		User:     w.ftp.User,
+		Password: w.ftp.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.ListFileInfos(ctx, w.ftp.RemotePathSend)
+}
+
+func loadWatched(path string) (map[string]watchedFile, error) {
+	files := make(map[string]watchedFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watched files '%s': %v", path, err)
+	}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal watched files '%s': %v", path, err)
+	}
+	return files, nil
+}
+
+// save writes the processed files atomically.
+func (w *watcher) save() {
+	err := func() error {
+		data, err := json.MarshalIndent(w.processed, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal watched files: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for watched files '%s': %v", w.path, err)
+		}
+		if err := util.WriteFileAtomic(w.path, data, 0600); err != nil {
+			return fmt.Errorf("failed to write watched files '%s': %v", w.path, err)
+		}
+		return nil
+	}()
+	if err != nil {
+		logger.AppLogger.Sugar().Errorf("Failed to record files processed by %s: %v", w.job.label, err)
+	}
+}
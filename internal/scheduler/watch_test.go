@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification_batch/internal/batch"
+	"notification_batch/internal/config"
+	"notification_batch/internal/ftp"
+)
+
+func TestWatcherProcess(t *testing.T) {
+	j := &job{batch: "spending_alert", name: JobSend, label: "Spending Alert Send Batch", watched: true}
+	useCatchUp(t, j, map[string]time.Time{})
+	var ran []string
+	failing := map[string]bool{"b.txt": true}
+	j.run = func(_ context.Context, _ *config.Config, opts batch.RunOptions) error {
+		ran = append(ran, opts.FileName)
+		if failing[opts.FileName] {
+			return errors.New("gateway unavailable")
+		}
+		return nil
+	}
+	w := &watcher{
+		job:         j,
+		stablePolls: 1,
+		pattern:     "*.txt",
+		path:        filepath.Join(t.TempDir(), "watch.json"),
+		pending:     make(map[string]*candidate),
+		processed:   make(map[string]watchedFile),
+		failed:      make(map[string]watchedFile),
+	}
+	at := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	a := ftp.FileInfo{Name: "a.txt", Size: 10, ModTime: at}
+	b := ftp.FileInfo{Name: "b.txt", Size: 20, ModTime: at}
+	ignored := ftp.FileInfo{Name: "b.tmp", Size: 5, ModTime: at}
+
+	steps := []struct {
+		name  string
+		files []ftp.FileInfo
+		fix   bool
+		want  []string
+	}{
+		{"new files wait to be stable", []ftp.FileInfo{a, b, ignored}, false, nil},
+		{"stable files run", []ftp.FileInfo{a, b, ignored}, false, []string{"a.txt", "b.txt"}},
+		{"failed file is not retried unchanged", []ftp.FileInfo{a, b}, false, nil},
+		{"changed file waits again", []ftp.FileInfo{a, {Name: "b.txt", Size: 30, ModTime: at}}, true, nil},
+		{"changed file runs", []ftp.FileInfo{a, {Name: "b.txt", Size: 30, ModTime: at}}, true, []string{"b.txt"}},
+		{"processed files are not run again", []ftp.FileInfo{a, {Name: "b.txt", Size: 30, ModTime: at}}, true, nil},
+	}
+	for _, step := range steps {
+		ran = nil
+		if step.fix {
+			delete(failing, "b.txt")
+		}
+		w.process(step.files)
+		if len(ran) != len(step.want) {
+			t.Fatalf("%s: ran %v, want %v", step.name, ran, step.want)
+		}
+		for i := range ran {
+			if ran[i] != step.want[i] {
+				t.Fatalf("%s: ran %v, want %v", step.name, ran, step.want)
+			}
+		}
+		if step.name == "failed file is not retried unchanged" {
+			saved, err := loadWatched(w.path)
+			if err != nil || len(saved) != 1 || saved["a.txt"].Size != 10 {
+				t.Fatalf("saved processed files = %v, %v, want only a.txt", saved, err)
+			}
+		}
+	}
+
+	saved, err := loadWatched(w.path)
+	if err != nil || len(saved) != 2 || saved["b.txt"].Size != 30 {
+		t.Errorf("saved processed files = %v, %v", saved, err)
+	}
+}
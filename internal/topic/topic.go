@@ -0,0 +1,39 @@
+package topic
+
+import (
+	"fmt"
+	"strings"
+
+	"notification_batch/internal/config"
+)
+
+// Resolve returns the topic code for a record, using the first matching rule or the batch default.
+func Resolve(cfg config.TopicConfig, fields map[string]string) string {
+	for _, rule := range cfg.Rules {
+		if strings.EqualFold(fields[rule.Field], rule.Equals) {
+			return rule.TopicCode
+		}
+	}
+	return cfg.Default
+}
+
+// Validate checks a batch topic configuration against the record fields available to its rules.
+func Validate(cfg config.TopicConfig, knownFields []string) error {
+	if strings.TrimSpace(cfg.Default) == "" {
+		return fmt.Errorf("topic.default must not be empty")
+	}
+
+	known := make(map[string]bool, len(knownFields))
+	for _, f := range knownFields {
+		known[f] = true
+	}
+	for i, rule := range cfg.Rules {
+		if !known[rule.Field] {
+			return fmt.Errorf("topic.rules[%d]: unknown record field '%s' (available: %s)", i, rule.Field, strings.Join(knownFields, ", "))
+		}
+		if strings.TrimSpace(rule.TopicCode) == "" {
+			return fmt.Errorf("topic.rules[%d]: topic_code must not be empty", i)
+		}
+	}
+	return nil
+}
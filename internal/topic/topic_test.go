@@ -0,0 +1,62 @@
+package topic
+
+import (
+	"testing"
+
+	"notification_batch/internal/config"
+)
+
+func TestResolve(t *testing.T) {
+	cfg := config.TopicConfig{
+		Default: "SPENDING_ALERT",
+		Rules: []config.TopicRule{
+			{Field: "channel", Equals: "ONLINE", TopicCode: "SPENDING_ONLINE"},
+			{Field: "merchant_category", Equals: "5411", TopicCode: "SPENDING_GROCERY"},
+			{Field: "channel", Equals: "POS", TopicCode: "SPENDING_POS"},
+		},
+	}
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"no rule matches", map[string]string{"channel": "ATM", "merchant_category": "6011"}, "SPENDING_ALERT"},
+		{"rule matches", map[string]string{"channel": "POS"}, "SPENDING_POS"},
+		{"match ignores case", map[string]string{"channel": "online"}, "SPENDING_ONLINE"},
+		{"first matching rule wins", map[string]string{"channel": "ONLINE", "merchant_category": "5411"}, "SPENDING_ONLINE"},
+		{"field missing from record", map[string]string{}, "SPENDING_ALERT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(cfg, tt.fields); got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := Resolve(config.TopicConfig{Default: "ENCB"}, map[string]string{"channel": "POS"}); got != "ENCB" {
+		t.Errorf("Resolve() without rules = %q, want the default", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	known := []string{"user_token", "date", "time", "channel"}
+	tests := []struct {
+		name    string
+		cfg     config.TopicConfig
+		wantErr bool
+	}{
+		{"default only", config.TopicConfig{Default: "SPENDING_ALERT"}, false},
+		{"rule on a known field", config.TopicConfig{Default: "SPENDING_ALERT", Rules: []config.TopicRule{{Field: "channel", Equals: "POS", TopicCode: "SPENDING_POS"}}}, false},
+		{"empty default", config.TopicConfig{Default: " "}, true},
+		{"rule on an unmapped record field", config.TopicConfig{Default: "SPENDING_ALERT", Rules: []config.TopicRule{{Field: "merchant_category", Equals: "5411", TopicCode: "SPENDING_GROCERY"}}}, true},
+		{"rule without topic code", config.TopicConfig{Default: "SPENDING_ALERT", Rules: []config.TopicRule{{Field: "channel", Equals: "POS"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.cfg, known); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package util
+
+import "strings"
+
+// Supported card number mask formats.
+const (
+	CardMaskFirst6Last4 = "first6_last4"
+	CardMaskLast4       = "last4"
+)
+
+const maskChar = "*"
+
+// MaskCardNo masks a card number according to the given format, defaulting to "last4".
+func MaskCardNo(cardNo, format string) string {
+	n := len(cardNo)
+	switch format {
+	case CardMaskFirst6Last4:
+		if n <= 10 {
+			return strings.Repeat(maskChar, n)
+		}
+		return cardNo[:6] + strings.Repeat(maskChar, n-10) + cardNo[n-4:]
+	default:
+		if n <= 4 {
+			return strings.Repeat(maskChar, n)
+		}
+		return strings.Repeat(maskChar, n-4) + cardNo[n-4:]
+	}
+}
+
+// IsValidCardMaskFormat reports whether format is a supported card mask format.
+func IsValidCardMaskFormat(format string) bool {
+	return format == CardMaskFirst6Last4 || format == CardMaskLast4
+}
@@ -0,0 +1,44 @@
+package util
+
+import "testing"
+
+func TestMaskCardNo(t *testing.T) {
+	tests := []struct {
+		name   string
+		cardNo string
+		format string
+		want   string
+	}{
+		{"last4", "4111111111111111", CardMaskLast4, "************1111"},
+		{"first6 last4", "4111111111111111", CardMaskFirst6Last4, "411111******1111"},
+		{"unknown format defaults to last4", "4111111111111111", "full", "************1111"},
+		{"19 digits", "6011000990139424123", CardMaskFirst6Last4, "601100*********4123"},
+		{"short value fully masked for last4", "1234", CardMaskLast4, "****"},
+		{"short value fully masked for first6 last4", "1234567890", CardMaskFirst6Last4, "**********"},
+		{"empty", "", CardMaskLast4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskCardNo(tt.cardNo, tt.format); got != tt.want {
+				t.Errorf("MaskCardNo(%q, %q) = %q, want %q", tt.cardNo, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidCardMaskFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   bool
+	}{
+		{CardMaskLast4, true},
+		{CardMaskFirst6Last4, true},
+		{"", false},
+		{"first4", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidCardMaskFormat(tt.format); got != tt.want {
+			t.Errorf("IsValidCardMaskFormat(%q) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	maxRequestIDNode    = 9
+	requestIDSeqModulus = 1000
+	requestIDTimeFormat = "20060102150405"
+	requestIDPrefix     = "RQ"
+	requestIDSeqDigits  = 3
+	requestIDNodeDigits = 1
+)
+
+var defaultRequestIDGenerator = NewRequestIDGenerator(0, time.Now)
+
+// RequestIDGenerator produces 20-character request IDs in the format "RQ" + YYYYMMDDHHMMSS +
+// 1-digit node ID + 3-digit sequence. The sequence restarts every second; when more than 1000
+// IDs are requested within a second the timestamp moves on to the next second, so a node never
+// repeats an ID as long as its clock does not go back.
+type RequestIDGenerator struct {
+	now    func() time.Time
+	node   int
+	mu     sync.Mutex
+	second int64
+	seq    int
+}
+
+// NewRequestIDGenerator creates a generator for node (0-9) using now as its clock.
+func NewRequestIDGenerator(node int, now func() time.Time) *RequestIDGenerator {
+	return &RequestIDGenerator{now: now, node: node % (maxRequestIDNode + 1), second: -1}
+}
+
+// Next returns the next request ID.
+func (g *RequestIDGenerator) Next() string {
+	now := g.now()
+
+	g.mu.Lock()
+	if sec := now.Unix(); sec > g.second {
+		g.second, g.seq = sec, 0
+	} else if g.seq++; g.seq == requestIDSeqModulus {
+		g.second, g.seq = g.second+1, 0
+	}
+	second, seq := g.second, g.seq
+	g.mu.Unlock()
+
+	timestamp := time.Unix(second, 0).In(now.Location()).Format(requestIDTimeFormat)
+	return fmt.Sprintf("%s%s%0*d%0*d", requestIDPrefix, timestamp, requestIDNodeDigits, g.node, requestIDSeqDigits, seq)
+}
+
+// SetRequestIDNode sets the node ID used by GenerateRequestID; it must be unique per running
+// instance and should be called once at startup, before any IDs are generated.
+func SetRequestIDNode(node int) error {
+	if node < 0 || node > maxRequestIDNode {
+		return fmt.Errorf("request ID node must be between 0 and %d, got %d", maxRequestIDNode, node)
+	}
+	defaultRequestIDGenerator = NewRequestIDGenerator(node, time.Now)
+	return nil
+}
+
+// GenerateRequestID generates a request ID with the format "RQ" + YYYYMMDDHHMMSS + node + sequence.
+func GenerateRequestID() string {
+	return defaultRequestIDGenerator.Next()
+}
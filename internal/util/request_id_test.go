@@ -0,0 +1,117 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedClock returns a clock stuck at t until it is moved with the returned setter.
+func fixedClock(t time.Time) (func() time.Time, func(time.Time)) {
+	var mu sync.Mutex
+	now := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return t
+	}
+	set := func(next time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		t = next
+	}
+	return now, set
+}
+
+func TestRequestIDFormat(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 30, 15, 0, time.UTC)
+	now, set := fixedClock(start)
+	g := NewRequestIDGenerator(7, now)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"first ID", start, "RQ202610190830157000"},
+		{"same second", start, "RQ202610190830157001"},
+		{"next second restarts the sequence", start.Add(time.Second), "RQ202610190830167000"},
+		{"clock going back keeps the last second", start, "RQ202610190830167001"},
+	}
+	for _, tt := range tests {
+		set(tt.at)
+		if got := g.Next(); got != tt.want {
+			t.Errorf("%s: Next() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequestIDUniqueness(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name    string
+		nodes   []int
+		perNode int
+	}{
+		{"one node within one second", []int{1}, 999},
+		{"one node beyond the sequence", []int{1}, 5000},
+		{"several nodes sharing a clock", []int{0, 1, 9}, 2500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := fixedClock(start)
+			seen := make(map[string]bool)
+			for _, node := range tt.nodes {
+				g := NewRequestIDGenerator(node, now)
+				for i := 0; i < tt.perNode; i++ {
+					id := g.Next()
+					if len(id) != 20 {
+						t.Fatalf("Next() = %s, want 20 characters", id)
+					}
+					if seen[id] {
+						t.Fatalf("Next() repeated %s", id)
+					}
+					seen[id] = true
+				}
+			}
+		})
+	}
+}
+
+func TestRequestIDConcurrent(t *testing.T) {
+	now, _ := fixedClock(time.Date(2026, 10, 19, 8, 30, 15, 0, time.UTC))
+	g := NewRequestIDGenerator(3, now)
+
+	const workers, perWorker = 8, 500
+	ids := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				ids <- g.Next()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("Next() repeated %s across goroutines", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSetRequestIDNode(t *testing.T) {
+	for _, node := range []int{-1, 10, 999} {
+		if err := SetRequestIDNode(node); err == nil {
+			t.Errorf("SetRequestIDNode(%d) error = nil, want an error", node)
+		}
+	}
+	if err := SetRequestIDNode(0); err != nil {
+		t.Errorf("SetRequestIDNode(0) error = %v", err)
+	}
+}
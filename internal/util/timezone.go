@@ -0,0 +1,33 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// location is the business time zone used for schedules, business dates and file names.
+var location = time.Local
+
+// SetTimezone sets the business time zone by IANA name, e.g. "Asia/Bangkok"; empty keeps the host's local zone.
+// It must be called at startup before any batch runs.
+func SetTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid timezone '%s': %v", name, err)
+	}
+	location = loc
+	return nil
+}
+
+// Location returns the business time zone.
+func Location() *time.Location {
+	return location
+}
+
+// Now returns the current time in the business time zone.
+func Now() time.Time {
+	return time.Now().In(location)
+}
@@ -0,0 +1,27 @@
+package util
+
+import "testing"
+
+func TestSetTimezone(t *testing.T) {
+	previous := location
+	t.Cleanup(func() { location = previous })
+
+	if err := SetTimezone(""); err != nil || Location() != previous {
+		t.Errorf("SetTimezone(\"\") = %v, zone %s, want the zone kept", err, Location())
+	}
+	if err := SetTimezone("Asia/Bangkok"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Location().String(); got != "Asia/Bangkok" {
+		t.Errorf("Location() = %s, want Asia/Bangkok", got)
+	}
+	if _, offset := Now().Zone(); offset != 7*60*60 {
+		t.Errorf("Now() offset = %ds, want +07:00", offset)
+	}
+	if err := SetTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Error("SetTimezone() of an unknown zone error = nil")
+	}
+	if got := Location().String(); got != "Asia/Bangkok" {
+		t.Errorf("Location() after an invalid zone = %s, want Asia/Bangkok", got)
+	}
+}
@@ -2,30 +2,11 @@ package util
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
-	"time"
 )
 
-const requestIDLength = 4
-
-// GenerateRequestID generates a request ID with the format "RQ" + YYYYMMDDHHMMSS + random 4 digits.
-func GenerateRequestID() string {
-	now := time.Now()
-	timestamp := now.Format("20060102150405") // Format: YYYYMMDDHHMMSS
-
-	b := make([]rune, requestIDLength)
-	var digitRunes = []rune("0123456789")
-
-	for i := range b {
-		b[i] = digitRunes[rand.Intn(len(digitRunes))]
-	}
-	randomDigits := string(b)
-
-	return fmt.Sprintf("RQ%s%s", timestamp, randomDigits)
-}
-
 // WriteResultToFile writes a slice of strings to a file.
+// Rows are written as is; processors mask card numbers when they parse the record.
 func WriteResultToFile(filePath string, results []string) error {
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -43,6 +24,21 @@ func WriteResultToFile(filePath string, results []string) error {
 	return nil
 }
 
+// WriteFileAtomic replaces the file at path with data by writing a temporary file next to it and
+// renaming it, so readers never see a partial file. The temporary file is removed on failure.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // safeSubstring is a helper function to extract a substring safely
 func SafeSubstring(s string, start, length int) string {
 	if start < 0 || start >= len(s) {
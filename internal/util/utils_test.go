@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteResultToFileKeepsDigitRuns(t *testing.T) {
+	rows := []string{
+		// Queue and run IDs start with a 14-digit timestamp, some of which pass a Luhn check.
+		"411111******1111,token,20261019,0800,token,Queued 20261019013009-3f2a9c1b,min_amount,spending_alert-20261019013830-586da045",
+		"************1111,token,20261019,0800,token,Not Triggered,last_login_within_days,spending_alert-20261019013830-586da045-000001",
+	}
+	path := filepath.Join(t.TempDir(), "result.txt")
+	if err := WriteResultToFile(path, rows); err != nil {
+		t.Fatalf("WriteResultToFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), strings.Join(rows, "\n")+"\n"; got != want {
+		t.Errorf("file content = %q, want %q", got, want)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFileAtomic() error = %v", err)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Errorf("file content = %q, %v, want %q", data, err, content)
+		}
+	}
+
+	// A directory in place of the file makes the rename fail.
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.MkdirAll(filepath.Join(blocked, "entry"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(blocked, []byte("data"), 0600); err == nil {
+		t.Error("WriteFileAtomic() over a directory error = nil")
+	}
+	if _, err := os.Stat(blocked + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestSafeSubstring(t *testing.T) {
+	tests := []struct {
+		s      string
+		start  int
+		length int
+		want   string
+	}{
+		{"abcdef", 0, 3, "abc"},
+		{"abcdef", 4, 10, "ef"},
+		{"abcdef", 6, 1, ""},
+		{"abcdef", -1, 2, ""},
+	}
+	for _, tt := range tests {
+		if got := SafeSubstring(tt.s, tt.start, tt.length); got != tt.want {
+			t.Errorf("SafeSubstring(%q, %d, %d) = %q, want %q", tt.s, tt.start, tt.length, got, tt.want)
+		}
+	}
+}